@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"purchase-record/config"
 	"purchase-record/docs"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/importexcel"
+	"purchase-record/internal/purchaseorders/orderstore"
 	"purchase-record/internal/router"
 
 	"github.com/gin-contrib/cors"
@@ -27,8 +34,18 @@ import (
 // @in header
 // @description Type "Bearer" followed by a space and JWT token.
 func main() {
+	settingsPath := flag.String(config.SettingsFlag, "", "Path of the settings workbook listing the purchase order workbooks, overrides "+config.SettingsEnv)
+	flag.Parse()
+
 	// Initialize configuration
 	config.InitSwaggerConfig()
+	config.InitStoreConfig()
+	config.InitBackupConfig()
+	config.InitImportConfig()
+	config.InitCacheConfig()
+	config.InitSettingsConfig(*settingsPath)
+	config.InitWatchConfig()
+	validateSettings()
 
 	// Programmatically set swagger info
 	docs.SwaggerInfo.Title = config.CF.Swagger.Title
@@ -41,8 +58,21 @@ func main() {
 	// Configure CORS
 	r.Use(cors.Default())
 
-	// Routes
-	router.RegisterRoutePurchaseOrder(r)
+	// Keep imported orders in SQLite when a database path is configured
+	var store orderstore.IOrderStoreRepository
+	if config.CF.Store.Path != "" {
+		var err error
+		store, err = orderstore.NewOrderStoreRepository(config.CF.Store.Path)
+		if err != nil {
+			log.Fatalf("Failed to open order store: %v", err)
+		}
+		defer store.Close()
+	}
+
+	// Routes, with the background work stopped when the server returns
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.RegisterRoutePurchaseOrder(ctx, r, store)
 
 	// Swagger endpoint
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -50,3 +80,23 @@ func main() {
 	// Run server
 	r.Run(":8080")
 }
+
+// validateSettings checks the settings workbook once at startup. A workbook
+// that is not configured or cannot be reached yet only leaves the settings
+// endpoint answering 503 until it can, while one that is there but cannot be
+// read as a settings workbook stops the server.
+func validateSettings() {
+	settings := config.CF.Settings
+	paths, err := importexcel.NewSettingPathService().GetSettingPath(settings.Path, models.SheetSelector{})
+	switch {
+	case errors.Is(err, importexcel.ErrSettingsNotConfigured):
+		log.Printf("No settings workbook configured with %s or -%s; /purchaseorders/setting answers 503 and no workbook is watched",
+			config.SettingsEnv, config.SettingsFlag)
+	case errors.Is(err, importexcel.ErrSettingsUnreachable):
+		log.Printf("Warning: settings workbook '%s' from the %s: %v", settings.Path, settings.From, err)
+	case err != nil:
+		log.Fatalf("Invalid settings workbook '%s' from the %s: %v", settings.Path, settings.From, err)
+	default:
+		log.Printf("Settings workbook '%s' from the %s lists %d workbooks", settings.Path, settings.From, len(paths))
+	}
+}
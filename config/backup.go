@@ -0,0 +1,74 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables of the backup settings
+const (
+	BackupDirEnv      = "PURCHASE_RECORD_BACKUP_DIR"
+	BackupKeepEnv     = "PURCHASE_RECORD_BACKUP_KEEP"
+	BackupKeepDaysEnv = "PURCHASE_RECORD_BACKUP_KEEP_DAYS"
+	MaxStalenessEnv   = "PURCHASE_RECORD_MAX_STALENESS"
+)
+
+// BackupConfig contains configuration for the backups of source workbooks
+type BackupConfig struct {
+	// Dir holds the backup versions
+	Dir string
+	// KeepVersions is how many versions of each file are kept, 0 for no limit
+	KeepVersions int
+	// KeepDays is how many days a version is kept, 0 for no limit
+	KeepDays int
+	// MaxStaleness is the age beyond which a backup or stored import is no
+	// longer served in place of an unreachable source, 0 for no limit
+	MaxStaleness time.Duration
+}
+
+// InitBackupConfig reads the backup settings from the environment, keeping
+// 10 versions for up to 30 days in ./backup unless told otherwise
+func InitBackupConfig() {
+	CF.Backup = BackupConfig{
+		Dir:          "backup",
+		KeepVersions: 10,
+		KeepDays:     30,
+	}
+	if dir := os.Getenv(BackupDirEnv); dir != "" {
+		CF.Backup.Dir = dir
+	}
+	readCount(BackupKeepEnv, &CF.Backup.KeepVersions)
+	readCount(BackupKeepDaysEnv, &CF.Backup.KeepDays)
+	readDuration(MaxStalenessEnv, &CF.Backup.MaxStaleness)
+}
+
+// readCount sets target from a non-negative whole number in the environment
+func readCount(env string, target *int) {
+	value := os.Getenv(env)
+	if value == "" {
+		return
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		log.Printf("Ignoring %s=%q, expected a whole number of 0 or more", env, value)
+		return
+	}
+	*target = count
+}
+
+// readDuration sets target from a non-negative duration such as "72h" in the
+// environment
+func readDuration(env string, target *time.Duration) {
+	value := os.Getenv(env)
+	if value == "" {
+		return
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Printf("Ignoring %s=%q, expected a duration such as 72h", env, value)
+		return
+	}
+	*target = duration
+}
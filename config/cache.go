@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// Environment variables of the parse cache settings
+const (
+	CacheEntriesEnv = "PURCHASE_RECORD_CACHE_ENTRIES"
+	CacheTTLEnv     = "PURCHASE_RECORD_CACHE_TTL"
+)
+
+// CacheConfig contains configuration for the cache of parsed workbooks
+type CacheConfig struct {
+	// MaxEntries is how many parsed workbooks are kept; 0 disables the cache.
+	// Each cached workbook holds all of its orders in memory.
+	MaxEntries int
+	// TTL is how long a parsed workbook is kept, 0 for no limit
+	TTL time.Duration
+}
+
+// InitCacheConfig reads the cache settings from the environment. The cache
+// is off unless a number of entries is given, since a cached workbook is held
+// in memory whole; entries are kept for up to 10 minutes unless told otherwise.
+func InitCacheConfig() {
+	CF.Cache = CacheConfig{
+		TTL: 10 * time.Minute,
+	}
+	readCount(CacheEntriesEnv, &CF.Cache.MaxEntries)
+	readDuration(CacheTTLEnv, &CF.Cache.TTL)
+}
@@ -0,0 +1,23 @@
+package config
+
+// MaxSourceMBEnv names the environment variable holding the largest source
+// workbook read, in megabytes
+const MaxSourceMBEnv = "PURCHASE_RECORD_MAX_SOURCE_MB"
+
+// ImportConfig contains configuration for reading source workbooks
+type ImportConfig struct {
+	// MaxSourceMB is the largest workbook read into memory, 0 for no limit
+	MaxSourceMB int
+}
+
+// InitImportConfig reads the import settings from the environment, allowing
+// workbooks of up to 100 MB unless told otherwise
+func InitImportConfig() {
+	CF.Import = ImportConfig{MaxSourceMB: 100}
+	readCount(MaxSourceMBEnv, &CF.Import.MaxSourceMB)
+}
+
+// MaxSourceSize returns the size limit in bytes
+func (c ImportConfig) MaxSourceSize() int64 {
+	return int64(c.MaxSourceMB) << 20
+}
@@ -0,0 +1,32 @@
+package config
+
+import "os"
+
+// SettingsEnv names the environment variable holding the settings workbook path
+const SettingsEnv = "PURCHASE_RECORD_SETTINGS"
+
+// SettingsFlag names the command line flag holding the settings workbook path,
+// which takes precedence over SettingsEnv
+const SettingsFlag = "settings"
+
+// SettingsConfig contains configuration for the settings workbook listing the
+// purchase order workbooks
+type SettingsConfig struct {
+	// Path of the settings workbook; it is not available when it is empty
+	Path string
+	// From describes where Path was configured, for diagnostics
+	From string
+}
+
+// InitSettingsConfig takes the settings workbook path from the command line
+// flag when it was given, or else from the environment
+func InitSettingsConfig(flagPath string) {
+	switch {
+	case flagPath != "":
+		CF.Settings = SettingsConfig{Path: flagPath, From: "flag -" + SettingsFlag}
+	case os.Getenv(SettingsEnv) != "":
+		CF.Settings = SettingsConfig{Path: os.Getenv(SettingsEnv), From: "environment variable " + SettingsEnv}
+	default:
+		CF.Settings = SettingsConfig{}
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "os"
+
+// StoreEnv names the environment variable holding the order store database path
+const StoreEnv = "PURCHASE_RECORD_DB"
+
+// StoreConfig contains configuration for the SQLite order store
+type StoreConfig struct {
+	// Path of the database file; the store is disabled when it is empty
+	Path string
+}
+
+// InitStoreConfig reads the order store settings from the environment
+func InitStoreConfig() {
+	CF.Store = StoreConfig{
+		Path: os.Getenv(StoreEnv),
+	}
+}
@@ -11,7 +11,13 @@ type SwaggerConfig struct {
 
 // Config holds all application configurations
 type Config struct {
-	Swagger SwaggerConfig
+	Swagger  SwaggerConfig
+	Store    StoreConfig
+	Backup   BackupConfig
+	Import   ImportConfig
+	Cache    CacheConfig
+	Settings SettingsConfig
+	Watch    WatchConfig
 }
 
 // CF is the global configuration instance
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// WatchIntervalEnv names the environment variable holding how often the
+// watched workbooks are checked
+const WatchIntervalEnv = "PURCHASE_RECORD_WATCH_INTERVAL"
+
+// WatchConfig contains configuration for the watcher that re-imports the
+// workbooks listed in the settings workbook when they change
+type WatchConfig struct {
+	// Interval is how often the paths are checked; 0 disables the watcher
+	Interval time.Duration
+}
+
+// InitWatchConfig reads the watcher settings from the environment, checking
+// the listed workbooks every minute unless told otherwise
+func InitWatchConfig() {
+	CF.Watch = WatchConfig{
+		Interval: time.Minute,
+	}
+	readDuration(WatchIntervalEnv, &CF.Watch.Interval)
+}
@@ -26,40 +26,937 @@ const docTemplate = `{
     "paths": {
         "/purchaseorders": {
             "post": {
-                "description": "Retrieves purchase order data from an Excel file located on a fixed network share path",
+                "description": "Retrieves purchase order data from an Excel file located on a fixed network share path.\nSend \"Accept: application/x-ndjson\" to receive one order per line while the workbook is still being read.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Import purchase orders from Excel file on network share",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Path to the Excel file",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path to an import profile (.yaml or .json) describing the workbook layout",
+                        "name": "profile",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact name of the data sheet",
+                        "name": "sheet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Regular expression matching the data sheet name",
+                        "name": "sheetPattern",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Zero-based index of the data sheet",
+                        "name": "sheetIndex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words to find in job ID, customer, product code, product description, PR or PO",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Sales teams to include",
+                        "name": "salesTeam",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Project managers to include",
+                        "name": "projectManager",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Purchasing officers to include",
+                        "name": "purchasing",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Customers to include",
+                        "name": "customer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Statuses to include",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Order types to include",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ordered quantity range such as 10..50, 10.. or ..50",
+                        "name": "ordered",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Remaining quantity range such as 1..",
+                        "name": "remain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PR date range of YYYY-MM-DD dates such as 2024-01-01..2024-03-31",
+                        "name": "prDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PO date range",
+                        "name": "poDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Request date range",
+                        "name": "requestDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PO receive date range",
+                        "name": "poReceiveDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Received date range",
+                        "name": "receivedDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stock picking out date range",
+                        "name": "stockPickingOutDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery date range",
+                        "name": "deliveryDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names, prefix with - for descending, such as customer,-po_date",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "pageNo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders per page, 0 returns every order",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/purchaseorderhandler.PageResponse"
+                        },
+                        "headers": {
+                            "Server-Timing": {
+                                "type": "string",
+                                "description": "Milliseconds spent reading, backing up and parsing the workbook"
+                            },
+                            "X-Backup-Age": {
+                                "type": "integer",
+                                "description": "Age of the backup or stored import in seconds"
+                            },
+                            "X-Backup-Error": {
+                                "type": "string",
+                                "description": "Why the source file was not backed up; the previous backup is kept"
+                            },
+                            "X-Backup-Time": {
+                                "type": "string",
+                                "description": "When the backup or stored import was made, RFC 3339"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit when the workbook was unchanged and its cached orders were served"
+                            },
+                            "X-Data-Origin": {
+                                "type": "string",
+                                "description": "Where the orders were read from: source, backup or store"
+                            },
+                            "X-Source-Error": {
+                                "type": "string",
+                                "description": "Why the source file could not be read"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/cache": {
+            "get": {
+                "description": "Reports how many parsed workbooks are cached and how often a request was served from the cache since start.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Report cache statistics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/models.CacheStats"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Drops the cached orders of an Excel file, or of every file when no path is given, so the next request parses it again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Clear the cache of parsed workbooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only drop the cached orders of this Excel file",
+                        "name": "path",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/export": {
+            "post": {
+                "description": "Writes the orders selected by the same search, filter, sort and paging parameters as the import endpoint as a download.\nThe .xlsx format has typed date and number cells, a frozen header row, an autofilter and rows coloured by status.\nCSV and TSV files are headed by the JSON field names and start with a UTF-8 byte order mark unless bom=false.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "text/csv",
+                    "text/tab-separated-values"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Export purchase orders to Excel, CSV or TSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Path to the Excel file",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "xlsx",
+                            "csv",
+                            "tsv"
+                        ],
+                        "type": "string",
+                        "default": "xlsx",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "JSON field names to export, in order; every field when omitted",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": ",",
+                        "description": "Single-character CSV delimiter",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Start CSV and TSV files with a UTF-8 byte order mark",
+                        "name": "bom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path to an import profile (.yaml or .json) describing the workbook layout",
+                        "name": "profile",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact name of the data sheet",
+                        "name": "sheet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words to find in job ID, customer, product code, product description, PR or PO",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Customers to include",
+                        "name": "customer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated JSON field names, prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "pageNo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders per page, 0 exports every order",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "Server-Timing": {
+                                "type": "string",
+                                "description": "Milliseconds spent reading, backing up and parsing the workbook"
+                            },
+                            "X-Backup-Age": {
+                                "type": "integer",
+                                "description": "Age of the backup or stored import in seconds"
+                            },
+                            "X-Backup-Error": {
+                                "type": "string",
+                                "description": "Why the source file was not backed up; the previous backup is kept"
+                            },
+                            "X-Backup-Time": {
+                                "type": "string",
+                                "description": "When the backup or stored import was made, RFC 3339"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit when the workbook was unchanged and its cached orders were served"
+                            },
+                            "X-Data-Origin": {
+                                "type": "string",
+                                "description": "Where the orders were read from: source, backup or store"
+                            },
+                            "X-Source-Error": {
+                                "type": "string",
+                                "description": "Why the source file could not be read"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/setting": {
+            "get": {
+                "description": "Retrieves the path of the purchase order Excel file from the configured settings workbook.\nAnswers 503 with the configured path, where it was configured and why it is unavailable when the settings workbook is not configured or cannot be reached.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Get the path of the purchase order Excel file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Exact name of the settings sheet",
+                        "name": "sheet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Regular expression matching the settings sheet name",
+                        "name": "sheetPattern",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Zero-based index of the settings sheet",
+                        "name": "sheetIndex",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/sheets": {
+            "get": {
+                "description": "Lists every sheet in the workbook with its row and column counts so the data sheet can be picked by name or index",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "purchaseorders"
                 ],
-                "summary": "Import purchase orders from Excel file on network share",
+                "summary": "List the sheets of an Excel file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Path to the Excel file",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.SheetInfo"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/snapshots": {
+            "get": {
+                "description": "Lists the snapshots recorded in the order store, newest first. A snapshot is saved for every import of a workbook whose content changed since its previous import.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "List import snapshots",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list snapshots of this Excel file",
+                        "name": "path",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.StoredImport"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/snapshots/diff": {
+            "get": {
+                "description": "Reports the orders added, removed and changed between two snapshots, with the old and new value of every changed field.\nOrders are matched by job ID and product code.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Compare two import snapshots",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the older snapshot",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the newer snapshot",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/models.SnapshotDiff"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/summary": {
+            "post": {
+                "description": "Groups the orders of an Excel file by customer, sales team, project manager, purchasing officer or status and returns line counts, quantity totals and completion percentages.\nThe search and filter parameters of the import endpoint narrow the orders first; paging and sorting are ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Summarize purchase orders",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Path to the Excel file",
                         "name": "path",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "customer",
+                            "sales_team",
+                            "project_manager",
+                            "purchasing",
+                            "status"
+                        ],
+                        "type": "string",
+                        "description": "Field to group by",
+                        "name": "groupBy",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path to an import profile (.yaml or .json) describing the workbook layout",
+                        "name": "profile",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact name of the data sheet",
+                        "name": "sheet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words to find in job ID, customer, product code, product description, PR or PO",
+                        "name": "search",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/models.OrderSummary"
+                            }
+                        },
+                        "headers": {
+                            "Server-Timing": {
+                                "type": "string",
+                                "description": "Milliseconds spent reading, backing up and parsing the workbook"
+                            },
+                            "X-Backup-Age": {
+                                "type": "integer",
+                                "description": "Age of the backup or stored import in seconds"
+                            },
+                            "X-Backup-Error": {
+                                "type": "string",
+                                "description": "Why the source file was not backed up; the previous backup is kept"
+                            },
+                            "X-Backup-Time": {
+                                "type": "string",
+                                "description": "When the backup or stored import was made, RFC 3339"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit when the workbook was unchanged and its cached orders were served"
+                            },
+                            "X-Data-Origin": {
+                                "type": "string",
+                                "description": "Where the orders were read from: source, backup or store"
+                            },
+                            "X-Source-Error": {
+                                "type": "string",
+                                "description": "Why the source file could not be read"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchaseorders/upload": {
+            "post": {
+                "description": "Reads the orders of an .xlsx file sent as multipart/form-data, for callers that cannot reach the network share.\nCells that could not be converted are listed under warnings as well as on their order.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchaseorders"
+                ],
+                "summary": "Import purchase orders from an uploaded Excel file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Excel workbook",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path to an import profile (.yaml or .json) describing the workbook layout",
+                        "name": "profile",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact name of the data sheet",
+                        "name": "sheet",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Regular expression matching the data sheet name",
+                        "name": "sheetPattern",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Zero-based index of the data sheet",
+                        "name": "sheetIndex",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/purchaseorderhandler.UploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/models.PurchaseOrder"
-                                }
+                                "type": "string"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "413": {
+                        "description": "Request Entity Too Large",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -70,31 +967,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/purchaseorders/setting": {
+        "/purchaseorders/watch": {
             "get": {
-                "description": "Retrieves the path of the purchase order Excel file",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the paths of the settings workbook with when each was last checked and re-imported, the version imported and why the last check failed, if it did.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "purchaseorders"
                 ],
-                "summary": "Get the path of the purchase order Excel file",
+                "summary": "Report the workbook watcher's last imports",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "$ref": "#/definitions/models.WatchStatus"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -107,6 +1001,99 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.CacheStats": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "integer"
+                },
+                "evictions": {
+                    "description": "Evictions counts entries dropped to make room, expired or invalidated",
+                    "type": "integer"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "maxEntries": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "ttlSeconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Date": {
+            "type": "object",
+            "properties": {
+                "time.Time": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "models.ImportWarning": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrderChange": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "key": {
+                    "type": "string"
+                },
+                "order": {
+                    "$ref": "#/definitions/models.PurchaseOrder"
+                }
+            }
+        },
+        "models.OrderSummary": {
+            "type": "object",
+            "properties": {
+                "groupBy": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryGroup"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/models.SummaryGroup"
+                }
+            }
+        },
         "models.PurchaseOrder": {
             "type": "object",
             "properties": {
@@ -114,6 +1101,9 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "delivery_date": {
+                    "$ref": "#/definitions/models.Date"
+                },
+                "delivery_log": {
                     "type": "string"
                 },
                 "distribution": {
@@ -129,16 +1119,16 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "po_date": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Date"
                 },
                 "po_receive_date": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Date"
                 },
                 "pr": {
                     "type": "string"
                 },
                 "pr_date": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Date"
                 },
                 "product_code": {
                     "type": "string"
@@ -156,7 +1146,7 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "received_date": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Date"
                 },
                 "remain": {
                     "type": "integer"
@@ -165,19 +1155,312 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "request_date": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Date"
                 },
                 "sales_team": {
                     "type": "string"
                 },
+                "shipments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Shipment"
+                    }
+                },
                 "status": {
                     "type": "string"
                 },
-                "stock_picking_out_date": {
+                "status_reason": {
                     "type": "string"
                 },
+                "stock_picking_out_date": {
+                    "$ref": "#/definitions/models.Date"
+                },
                 "type": {
                     "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportWarning"
+                    }
+                }
+            }
+        },
+        "models.SheetInfo": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "visible": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Shipment": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "$ref": "#/definitions/models.Date"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SnapshotDiff": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PurchaseOrder"
+                    }
+                },
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderChange"
+                    }
+                },
+                "from": {
+                    "$ref": "#/definitions/models.StoredImport"
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PurchaseOrder"
+                    }
+                },
+                "to": {
+                    "$ref": "#/definitions/models.StoredImport"
+                },
+                "unchanged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StoredImport": {
+            "type": "object",
+            "properties": {
+                "contentHash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "importedAt": {
+                    "type": "string"
+                },
+                "rowCount": {
+                    "type": "integer"
+                },
+                "sourcePath": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SummaryGroup": {
+            "type": "object",
+            "properties": {
+                "completedLines": {
+                    "type": "integer"
+                },
+                "completionPercent": {
+                    "type": "number"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "integer"
+                },
+                "ordered": {
+                    "type": "integer"
+                },
+                "received": {
+                    "type": "integer"
+                },
+                "remain": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WatchStatus": {
+            "type": "object",
+            "properties": {
+                "checkedAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error is why the settings workbook could not be read on the last pass,\nin which case the paths of the pass before are kept",
+                    "type": "string"
+                },
+                "intervalSeconds": {
+                    "type": "integer"
+                },
+                "paths": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WatchedPath"
+                    }
+                },
+                "settings": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WatchedPath": {
+            "type": "object",
+            "properties": {
+                "backupError": {
+                    "type": "string"
+                },
+                "checkedAt": {
+                    "type": "string"
+                },
+                "contentHash": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error is why the last check failed; the path is imported again on the\nnext check",
+                    "type": "string"
+                },
+                "importedAt": {
+                    "description": "ImportedAt is when the workbook was last re-imported after a change",
+                    "type": "string"
+                },
+                "imports": {
+                    "type": "integer"
+                },
+                "modTime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "rowCount": {
+                    "type": "integer"
+                },
+                "sheet": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size and ModTime identify the version last imported",
+                    "type": "integer"
+                }
+            }
+        },
+        "purchaseorderhandler.PageLinks": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                },
+                "self": {
+                    "type": "string"
+                }
+            }
+        },
+        "purchaseorderhandler.PageResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PurchaseOrder"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/purchaseorderhandler.PageLinks"
+                },
+                "pageNo": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "source": {
+                    "$ref": "#/definitions/purchaseorderhandler.SourceInfo"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "totalPages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "purchaseorderhandler.SourceInfo": {
+            "type": "object",
+            "properties": {
+                "backupAgeSeconds": {
+                    "description": "BackupAgeSeconds is how old the backup or stored import is",
+                    "type": "integer"
+                },
+                "backupError": {
+                    "description": "BackupError is set when the source file could not be backed up, so the\nprevious backup is kept",
+                    "type": "string"
+                },
+                "backupTime": {
+                    "description": "BackupTime is when the backup or stored import was made, set unless the\norigin is the source",
+                    "type": "string"
+                },
+                "cached": {
+                    "description": "Cached is set when the source file was unchanged since its orders were\ncached, so it was neither read nor backed up again",
+                    "type": "boolean"
+                },
+                "origin": {
+                    "type": "string",
+                    "example": "backup"
+                },
+                "sourceError": {
+                    "description": "SourceError is why the requested file could not be read",
+                    "type": "string"
+                }
+            }
+        },
+        "purchaseorderhandler.UploadResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PurchaseOrder"
+                    }
+                },
+                "fileName": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportWarning"
+                    }
                 }
             }
         }
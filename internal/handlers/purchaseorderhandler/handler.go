@@ -1,111 +1,866 @@
 package purchaseorderhandler
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
-	"os"
+	"purchase-record/config"
+	"purchase-record/internal/models"
 	"purchase-record/internal/purchaseorders/importexcel"
+	"purchase-record/internal/purchaseorders/ordercache"
+	"purchase-record/internal/purchaseorders/orderexport"
+	"purchase-record/internal/purchaseorders/orderquery"
+	"purchase-record/internal/purchaseorders/orderstore"
+	"purchase-record/internal/purchaseorders/orderwatch"
 	"purchase-record/internal/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/sync/singleflight"
+)
+
+// MIMENDJSON is the content type of newline-delimited JSON responses
+const MIMENDJSON = "application/x-ndjson"
+
+// Headers that carry SourceInfo for responses that have no JSON envelope such
+// as NDJSON and exports
+const (
+	HeaderDataOrigin  = "X-Data-Origin"
+	HeaderBackupTime  = "X-Backup-Time"
+	HeaderBackupAge   = "X-Backup-Age"
+	HeaderSourceError = "X-Source-Error"
+	HeaderBackupError = "X-Backup-Error"
+	HeaderCache       = "X-Cache"
 )
 
 type IHandler interface {
 	GetOrdersFromNetworkPath(c *gin.Context)
+	GetSummary(c *gin.Context)
+	ExportOrders(c *gin.Context)
+	UploadOrders(c *gin.Context)
+	ListSnapshots(c *gin.Context)
+	DiffSnapshots(c *gin.Context)
+	GetCacheStats(c *gin.Context)
+	InvalidateCache(c *gin.Context)
+	GetWatchStatus(c *gin.Context)
+	GetSheets(c *gin.Context)
 	GetSettingPath(c *gin.Context)
+	Watch(ctx context.Context)
 }
 
 type Handler struct {
 	NetworkPathService importexcel.INetworkPathService
 	SettingPathService importexcel.ISettingPathService
+	// MaxStaleness is the oldest backup or stored import served in place of
+	// an unreachable file, 0 for no limit
+	MaxStaleness time.Duration
+	// MaxSourceSize is the largest workbook read into memory in bytes, 0 for
+	// no limit
+	MaxSourceSize int64
+	// Settings locates the settings workbook listing the order workbooks
+	Settings config.SettingsConfig
+	// Watcher re-imports the workbooks listed in the settings workbook when
+	// they change, nil when watching is not configured
+	Watcher orderwatch.IWatcher
+
+	// loads lets concurrent requests for the same workbook share one load
+	loads singleflight.Group
 }
 
 func NewHandler() IHandler {
 	return &Handler{
 		NetworkPathService: importexcel.NewNetworkPathService(),
 		SettingPathService: importexcel.NewSettingPathService(),
+		MaxStaleness:       config.CF.Backup.MaxStaleness,
+		MaxSourceSize:      config.CF.Import.MaxSourceSize(),
+		Settings:           config.CF.Settings,
+	}
+}
+
+// NewHandlerWithStore keeps imported orders in store so they can still be
+// served while the network share is down. A nil store disables it. Parsed
+// workbooks are cached, and the workbooks listed in the settings workbook
+// watched, as configured.
+func NewHandlerWithStore(store orderstore.IOrderStoreRepository) IHandler {
+	networkPathService := importexcel.NewNetworkPathServiceWithStorage(store, ordercache.NewOrderCache(config.CF.Cache))
+	settingPathService := importexcel.NewSettingPathService()
+	loader := importexcel.NewWorkbookLoader(networkPathService, config.CF.Import.MaxSourceSize())
+	return &Handler{
+		NetworkPathService: networkPathService,
+		SettingPathService: settingPathService,
+		MaxStaleness:       config.CF.Backup.MaxStaleness,
+		MaxSourceSize:      config.CF.Import.MaxSourceSize(),
+		Settings:           config.CF.Settings,
+		Watcher:            orderwatch.NewWatcher(config.CF.Watch, config.CF.Settings.Path, settingPathService, loader),
 	}
 }
 
+// Watch runs the watcher until ctx is done. It returns right away when
+// watching is not configured.
+func (h *Handler) Watch(ctx context.Context) {
+	if h.Watcher == nil {
+		return
+	}
+	h.Watcher.Run(ctx)
+}
+
 // GetOrdersFromNetworkPath godoc
 // @Summary Import purchase orders from Excel file on network share
-// @Description Retrieves purchase order data from an Excel file located on a fixed network share path
+// @Description Retrieves purchase order data from an Excel file located on a fixed network share path.
+// @Description Send "Accept: application/x-ndjson" to receive one order per line while the workbook is still being read.
 // @Tags purchaseorders
 // @Accept json
 // @Produce json
+// @Produce application/x-ndjson
 // @Param path query string false "Path to the Excel file"
-// @Success 200 {object} map[string][]models.PurchaseOrder
+// @Param profile query string false "Path to an import profile (.yaml or .json) describing the workbook layout"
+// @Param sheet query string false "Exact name of the data sheet"
+// @Param sheetPattern query string false "Regular expression matching the data sheet name"
+// @Param sheetIndex query int false "Zero-based index of the data sheet"
+// @Param search query string false "Words to find in job ID, customer, product code, product description, PR or PO"
+// @Param salesTeam query []string false "Sales teams to include" collectionFormat(multi)
+// @Param projectManager query []string false "Project managers to include" collectionFormat(multi)
+// @Param purchasing query []string false "Purchasing officers to include" collectionFormat(multi)
+// @Param customer query []string false "Customers to include" collectionFormat(multi)
+// @Param status query []string false "Statuses to include" collectionFormat(multi)
+// @Param type query []string false "Order types to include" collectionFormat(multi)
+// @Param ordered query string false "Ordered quantity range such as 10..50, 10.. or ..50"
+// @Param remain query string false "Remaining quantity range such as 1.."
+// @Param prDate query string false "PR date range of YYYY-MM-DD dates such as 2024-01-01..2024-03-31"
+// @Param poDate query string false "PO date range"
+// @Param requestDate query string false "Request date range"
+// @Param poReceiveDate query string false "PO receive date range"
+// @Param receivedDate query string false "Received date range"
+// @Param stockPickingOutDate query string false "Stock picking out date range"
+// @Param deliveryDate query string false "Delivery date range"
+// @Param sort query string false "Comma-separated JSON field names, prefix with - for descending, such as customer,-po_date"
+// @Param pageNo query int false "Page number, starting at 1"
+// @Param pageSize query int false "Orders per page, 0 returns every order"
+// @Success 200 {object} purchaseorderhandler.PageResponse
+// @Header 200 {string} X-Data-Origin "Where the orders were read from: source, backup or store"
+// @Header 200 {string} X-Backup-Time "When the backup or stored import was made, RFC 3339"
+// @Header 200 {integer} X-Backup-Age "Age of the backup or stored import in seconds"
+// @Header 200 {string} X-Source-Error "Why the source file could not be read"
+// @Header 200 {string} X-Backup-Error "Why the source file was not backed up; the previous backup is kept"
+// @Header 200 {string} Server-Timing "Milliseconds spent reading, backing up and parsing the workbook"
+// @Header 200 {string} X-Cache "hit when the workbook was unchanged and its cached orders were served"
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /purchaseorders [post]
 func (h *Handler) GetOrdersFromNetworkPath(c *gin.Context) {
-	// Try to get path from query parameters first
-	filePath := c.Query("path")
+	request, ok := bindImportRequest(c)
+	if !ok {
+		return
+	}
+	source, ok := h.loadSource(c, &request)
+	if !ok {
+		return
+	}
 
-	// If path is not in query, check the request body
-	if filePath == "" {
-		// Define a struct to bind the JSON body
-		var requestBody struct {
-			Path string `json:"path"`
-		}
+	// Stream one order per line when the client asks for NDJSON
+	if c.NegotiateFormat(binding.MIMEJSON, MIMENDJSON) == MIMENDJSON {
+		h.streamOrders(c, source, request)
+		return
+	}
 
-		// Try to bind the JSON body
-		if err := c.ShouldBindJSON(&requestBody); err == nil && requestBody.Path != "" {
-			filePath = requestBody.Path
-		}
+	// Pass the workbook to the service with the search and paging query
+	start := time.Now()
+	page, err := h.NetworkPathService.QueryOrders(source.Workbook, request.ImportOptions, request.RequestQuery)
+	source.Timings.since(PhaseParse, start)
+	source.Timings.report(c, request.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if filePath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Path is required"})
+	response := newPageResponse(c, request, page)
+	response.Source = source.Info
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSummary godoc
+// @Summary Summarize purchase orders
+// @Description Groups the orders of an Excel file by customer, sales team, project manager, purchasing officer or status and returns line counts, quantity totals and completion percentages.
+// @Description The search and filter parameters of the import endpoint narrow the orders first; paging and sorting are ignored.
+// @Tags purchaseorders
+// @Accept json
+// @Produce json
+// @Param path query string false "Path to the Excel file"
+// @Param groupBy query string true "Field to group by" Enums(customer, sales_team, project_manager, purchasing, status)
+// @Param profile query string false "Path to an import profile (.yaml or .json) describing the workbook layout"
+// @Param sheet query string false "Exact name of the data sheet"
+// @Param search query string false "Words to find in job ID, customer, product code, product description, PR or PO"
+// @Success 200 {object} map[string]models.OrderSummary
+// @Header 200 {string} X-Data-Origin "Where the orders were read from: source, backup or store"
+// @Header 200 {string} X-Backup-Time "When the backup or stored import was made, RFC 3339"
+// @Header 200 {integer} X-Backup-Age "Age of the backup or stored import in seconds"
+// @Header 200 {string} X-Source-Error "Why the source file could not be read"
+// @Header 200 {string} X-Backup-Error "Why the source file was not backed up; the previous backup is kept"
+// @Header 200 {string} Server-Timing "Milliseconds spent reading, backing up and parsing the workbook"
+// @Header 200 {string} X-Cache "hit when the workbook was unchanged and its cached orders were served"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/summary [post]
+func (h *Handler) GetSummary(c *gin.Context) {
+	var request summaryRequest
+	if !bindRequest(c, &request, &request.importRequest) {
+		return
+	}
+	if _, err := orderquery.NewSummarizer(request.GroupBy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source, ok := h.loadSource(c, &request.importRequest)
+	if !ok {
 		return
 	}
 
-	// First try to read from the original file path
-	_, err := os.Stat(filePath)
+	start := time.Now()
+	summary, err := h.NetworkPathService.SummarizeOrders(source.Workbook, request.ImportOptions, request.RequestQuery, request.GroupBy)
+	source.Timings.since(PhaseParse, start)
+	source.Timings.report(c, request.Path)
 	if err != nil {
-		// If original file doesn't exist, try to get the latest backup
-		backupPath, err := utils.GetLatestBackupFile(filePath)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find original file or backup: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary, "source": source.Info})
+}
+
+// ExportOrders godoc
+// @Summary Export purchase orders to Excel, CSV or TSV
+// @Description Writes the orders selected by the same search, filter, sort and paging parameters as the import endpoint as a download.
+// @Description The .xlsx format has typed date and number cells, a frozen header row, an autofilter and rows coloured by status.
+// @Description CSV and TSV files are headed by the JSON field names and start with a UTF-8 byte order mark unless bom=false.
+// @Tags purchaseorders
+// @Accept json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Produce text/tab-separated-values
+// @Param path query string false "Path to the Excel file"
+// @Param format query string false "Export format" Enums(xlsx, csv, tsv) default(xlsx)
+// @Param columns query []string false "JSON field names to export, in order; every field when omitted" collectionFormat(csv)
+// @Param delimiter query string false "Single-character CSV delimiter" default(,)
+// @Param bom query bool false "Start CSV and TSV files with a UTF-8 byte order mark" default(true)
+// @Param profile query string false "Path to an import profile (.yaml or .json) describing the workbook layout"
+// @Param sheet query string false "Exact name of the data sheet"
+// @Param search query string false "Words to find in job ID, customer, product code, product description, PR or PO"
+// @Param customer query []string false "Customers to include" collectionFormat(multi)
+// @Param sort query string false "Comma-separated JSON field names, prefix with - for descending"
+// @Param pageNo query int false "Page number, starting at 1"
+// @Param pageSize query int false "Orders per page, 0 exports every order"
+// @Success 200 {file} file
+// @Header 200 {string} X-Data-Origin "Where the orders were read from: source, backup or store"
+// @Header 200 {string} X-Backup-Time "When the backup or stored import was made, RFC 3339"
+// @Header 200 {integer} X-Backup-Age "Age of the backup or stored import in seconds"
+// @Header 200 {string} X-Source-Error "Why the source file could not be read"
+// @Header 200 {string} X-Backup-Error "Why the source file was not backed up; the previous backup is kept"
+// @Header 200 {string} Server-Timing "Milliseconds spent reading, backing up and parsing the workbook"
+// @Header 200 {string} X-Cache "hit when the workbook was unchanged and its cached orders were served"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/export [post]
+func (h *Handler) ExportOrders(c *gin.Context) {
+	var request exportRequest
+	if !bindRequest(c, &request, &request.importRequest) {
+		return
+	}
+	opts := request.exportOptions()
+	if err := orderexport.ValidateOptions(opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source, ok := h.loadSource(c, &request.importRequest)
+	if !ok {
+		return
+	}
+
+	exporter, err := orderexport.NewExporter(c.Writer, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer exporter.Close()
+
+	c.Header("Content-Type", orderexport.ContentType(opts.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="purchaseorders-%s.%s"`,
+		time.Now().Format("20060102-150405"), orderexport.FileExtension(opts.Format)))
+	c.Status(http.StatusOK)
+
+	start := time.Now()
+	err = h.NetworkPathService.StreamOrders(source.Workbook, request.ImportOptions, request.RequestQuery, exporter.Write)
+	if err == nil {
+		err = exporter.Finish()
+	}
+	source.Timings.since(PhaseParse, start)
+	source.Timings.report(c, request.Path)
+	if err != nil {
+		// Exports are buffered, so most failures happen before anything is sent
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		filePath = backupPath
-	} else {
-		// If original file exists, create a backup
-		_, err = utils.BackupFile(filePath)
-		if err != nil {
-			// Log the backup error but continue with the original file
-			// You might want to add proper logging here
-			_ = err
+		// Otherwise the download is cut short
+		_ = c.Error(err)
+	}
+}
+
+// UploadOrders godoc
+// @Summary Import purchase orders from an uploaded Excel file
+// @Description Reads the orders of an .xlsx file sent as multipart/form-data, for callers that cannot reach the network share.
+// @Description Cells that could not be converted are listed under warnings as well as on their order.
+// @Tags purchaseorders
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Excel workbook"
+// @Param profile formData string false "Path to an import profile (.yaml or .json) describing the workbook layout"
+// @Param sheet formData string false "Exact name of the data sheet"
+// @Param sheetPattern formData string false "Regular expression matching the data sheet name"
+// @Param sheetIndex formData int false "Zero-based index of the data sheet"
+// @Success 200 {object} purchaseorderhandler.UploadResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Router /purchaseorders/upload [post]
+func (h *Handler) UploadOrders(c *gin.Context) {
+	// The upload is held in memory or spooled to disk before it is read, so
+	// a workbook over the limit for the share is refused as it arrives
+	if h.MaxSourceSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxSourceSize+maxUploadOverhead)
+	}
+
+	var opts models.ImportOptions
+	if err := c.ShouldBind(&opts); err != nil {
+		if h.uploadTooLarge(c, err) {
+			return
 		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form fields: " + err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if h.uploadTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An .xlsx file is required in the 'file' field: " + err.Error()})
+		return
+	}
+	if h.MaxSourceSize > 0 && fileHeader.Size > h.MaxSourceSize {
+		h.uploadTooLarge(c, &http.MaxBytesError{Limit: h.MaxSourceSize})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// The workbook comes from the caller, so problems with it are client errors
+	orders, err := h.NetworkPathService.ImportOrdersFromReader(file, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to import '" + fileHeader.Filename + "': " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUploadResponse(fileHeader.Filename, orders))
+}
+
+// maxUploadOverhead is the room left in an upload's body for the multipart
+// headers and form fields around a workbook of MaxSourceSize bytes
+const maxUploadOverhead = 64 << 10
+
+// uploadTooLarge answers with 413 when err is an upload over MaxSourceSize
+func (h *Handler) uploadTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": fmt.Sprintf("%v: the uploaded workbook is over the limit of %d bytes", utils.ErrSourceTooLarge, h.MaxSourceSize),
+	})
+	return true
+}
+
+// ListSnapshots godoc
+// @Summary List import snapshots
+// @Description Lists the snapshots recorded in the order store, newest first. A snapshot is saved for every import of a workbook whose content changed since its previous import.
+// @Tags purchaseorders
+// @Produce json
+// @Param path query string false "Only list snapshots of this Excel file"
+// @Success 200 {object} map[string][]models.StoredImport
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/snapshots [get]
+func (h *Handler) ListSnapshots(c *gin.Context) {
+	snapshots, err := h.NetworkPathService.ListSnapshots(c.Query("path"))
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": snapshots})
+}
+
+// DiffSnapshots godoc
+// @Summary Compare two import snapshots
+// @Description Reports the orders added, removed and changed between two snapshots, with the old and new value of every changed field.
+// @Description Orders are matched by job ID and product code.
+// @Tags purchaseorders
+// @Produce json
+// @Param from query int true "ID of the older snapshot"
+// @Param to query int true "ID of the newer snapshot"
+// @Success 200 {object} map[string]models.SnapshotDiff
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/snapshots/diff [get]
+func (h *Handler) DiffSnapshots(c *gin.Context) {
+	var request snapshotDiffRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if request.From <= 0 || request.To <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Snapshot IDs 'from' and 'to' are required"})
+		return
+	}
+
+	diff, err := h.NetworkPathService.DiffSnapshots(request.From, request.To)
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// GetCacheStats godoc
+// @Summary Report cache statistics
+// @Description Reports how many parsed workbooks are cached and how often a request was served from the cache since start.
+// @Tags purchaseorders
+// @Produce json
+// @Success 200 {object} map[string]models.CacheStats
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/cache [get]
+func (h *Handler) GetCacheStats(c *gin.Context) {
+	stats, err := h.NetworkPathService.CacheStats()
+	if err != nil {
+		c.JSON(cacheErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
 
-	// Pass the file path to the service - no filtering, get all orders
-	orders, err := h.NetworkPathService.GetOrdersFromPath(filePath)
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// InvalidateCache godoc
+// @Summary Clear the cache of parsed workbooks
+// @Description Drops the cached orders of an Excel file, or of every file when no path is given, so the next request parses it again.
+// @Tags purchaseorders
+// @Produce json
+// @Param path query string false "Only drop the cached orders of this Excel file"
+// @Success 200 {object} map[string]int
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/cache [delete]
+func (h *Handler) InvalidateCache(c *gin.Context) {
+	removed, err := h.NetworkPathService.InvalidateCache(c.Query("path"))
+	if err != nil {
+		c.JSON(cacheErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// GetWatchStatus godoc
+// @Summary Report the workbook watcher's last imports
+// @Description Lists the paths of the settings workbook with when each was last checked and re-imported, the version imported and why the last check failed, if it did.
+// @Tags purchaseorders
+// @Produce json
+// @Success 200 {object} map[string]models.WatchStatus
+// @Failure 503 {object} map[string]string
+// @Router /purchaseorders/watch [get]
+func (h *Handler) GetWatchStatus(c *gin.Context) {
+	if h.Watcher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The workbook watcher is not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.Watcher.Status()})
+}
+
+// GetSheets godoc
+// @Summary List the sheets of an Excel file
+// @Description Lists every sheet in the workbook with its row and column counts so the data sheet can be picked by name or index
+// @Tags purchaseorders
+// @Produce json
+// @Param path query string true "Path to the Excel file"
+// @Success 200 {object} map[string][]models.SheetInfo
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /purchaseorders/sheets [get]
+func (h *Handler) GetSheets(c *gin.Context) {
+	filePath := c.Query("path")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Path is required"})
+		return
+	}
+
+	sheets, err := h.NetworkPathService.GetSheetsFromPath(filePath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": orders})
+	c.JSON(http.StatusOK, gin.H{"data": sheets})
 }
 
 // GetSettingPath godoc
 // @Summary Get the path of the purchase order Excel file
-// @Description Retrieves the path of the purchase order Excel file
+// @Description Retrieves the path of the purchase order Excel file from the configured settings workbook.
+// @Description Answers 503 with the configured path, where it was configured and why it is unavailable when the settings workbook is not configured or cannot be reached.
 // @Tags purchaseorders
 // @Accept json
 // @Produce json
+// @Param sheet query string false "Exact name of the settings sheet"
+// @Param sheetPattern query string false "Regular expression matching the settings sheet name"
+// @Param sheetIndex query int false "Zero-based index of the settings sheet"
 // @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /purchaseorders/setting [get]
 func (h *Handler) GetSettingPath(c *gin.Context) {
-	// Use a fixed path for settings
-	filePath := ``
+	var sheet models.SheetSelector
+	if err := c.ShouldBindQuery(&sheet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
 
-	settings, err := h.SettingPathService.GetSettingPath(filePath)
+	settings, err := h.SettingPathService.GetSettingPath(h.Settings.Path, sheet)
 	if err != nil {
+		if reason, unavailable := settingsUnavailable(err); unavailable {
+			body := gin.H{
+				"error":  "Settings workbook is unavailable: " + err.Error(),
+				"reason": reason,
+				"path":   h.Settings.Path,
+				"from":   h.Settings.From,
+			}
+			if h.Settings.Path == "" {
+				body["hint"] = fmt.Sprintf("Set the %s environment variable or the -%s flag to the settings workbook path",
+					config.SettingsEnv, config.SettingsFlag)
+			}
+			c.JSON(http.StatusServiceUnavailable, body)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get setting path: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": settings})
 }
+
+// loadSource loads the requested workbook with importSource and reports where
+// it came from in the response headers. Concurrent requests for the same path
+// and options share one load. When no workbook can be loaded it writes the
+// error response and returns false.
+func (h *Handler) loadSource(c *gin.Context, request *importRequest) (orderSource, bool) {
+	result, err, _ := h.loads.Do(loadKey(request.Path, request.ImportOptions), func() (any, error) {
+		return h.importSource(request.Path, request.ImportOptions)
+	})
+	if err != nil {
+		var failure *sourceFailure
+		if errors.As(err, &failure) {
+			c.JSON(failure.status, failure.body)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return orderSource{}, false
+	}
+
+	// The workbook is shared, but every request times its own parse
+	source := result.(orderSource)
+	source.Timings = source.Timings.clone()
+	if source.Info.Origin == OriginBackup {
+		// Orders read from the backup are stored as an import of the original
+		request.Source = request.Path
+	}
+	source.Info.setHeaders(c)
+	c.Header(HeaderServerTiming, source.Timings.String())
+	return source, true
+}
+
+// importSource loads the workbook at filePath with the WorkbookLoader, which
+// reads it into memory once, or takes its cached orders when it is unchanged,
+// and backs it up. When the file is unreachable it falls back to the latest
+// backup, or else to the service's stored orders for the path. The returned
+// *sourceFailure answers with 503 when that copy is older than MaxStaleness,
+// and with 500 when there is none.
+func (h *Handler) importSource(filePath string, opts models.ImportOptions) (orderSource, error) {
+	timings := &importTimings{}
+
+	// First try to read the original file, in one pass over the network
+	loaded, sourceErr := importexcel.NewWorkbookLoader(h.NetworkPathService, h.MaxSourceSize).Load(filePath, opts)
+	timings.add(PhaseRead, loaded.ReadTime)
+	if errors.Is(sourceErr, utils.ErrSourceTooLarge) {
+		return orderSource{}, &sourceFailure{status: http.StatusInternalServerError, body: gin.H{"error": sourceErr.Error()}}
+	}
+	if sourceErr != nil {
+		start := time.Now()
+		source, err := h.fallbackSource(filePath, opts, sourceErr)
+		timings.since(PhaseFallback, start)
+		if err != nil {
+			return orderSource{}, &sourceFailure{status: http.StatusInternalServerError, body: gin.H{
+				"error": "Failed to find original file or backup: " + err.Error(),
+			}}
+		}
+		if h.MaxStaleness > 0 && time.Since(*source.Info.BackupTime) > h.MaxStaleness {
+			return orderSource{}, &sourceFailure{status: http.StatusServiceUnavailable, body: gin.H{
+				"error": fmt.Sprintf("Source file is unreachable and the latest %s from %s is older than %s",
+					source.Info.Origin, source.Info.BackupTime.Format(time.RFC3339), h.MaxStaleness),
+				"sourceError": source.Info.SourceError,
+			}}
+		}
+		source.Timings = timings
+		return source, nil
+	}
+
+	if !loaded.Cached {
+		timings.add(PhaseBackup, loaded.BackupTime)
+	}
+	// A failed backup does not stop the import, but the caller learns the
+	// backup is older
+	info := SourceInfo{Origin: OriginSource, Cached: loaded.Cached, BackupError: loaded.BackupError}
+	return orderSource{Workbook: loaded.Workbook, Info: info, Timings: timings}, nil
+}
+
+// loadKey identifies the loads that can be shared: the same file validated
+// against the same profile and sheet
+func loadKey(filePath string, opts models.ImportOptions) string {
+	sheetIndex := ""
+	if opts.SheetIndex != nil {
+		sheetIndex = strconv.Itoa(*opts.SheetIndex)
+	}
+	return strings.Join([]string{filePath, opts.Profile, opts.Sheet, opts.SheetPattern, sheetIndex}, "\x00")
+}
+
+// sourceFailure is the error response of a request whose workbook could not
+// be loaded
+type sourceFailure struct {
+	status int
+	body   gin.H
+}
+
+func (f *sourceFailure) Error() string {
+	return fmt.Sprint(f.body["error"])
+}
+
+// fallbackSource reads the latest backup of an unreachable file, or points to
+// its last stored import when there is no backup. A backup made by an earlier
+// release is only used once it passes the checks a new backup would.
+func (h *Handler) fallbackSource(filePath string, opts models.ImportOptions, sourceErr error) (orderSource, error) {
+	legacyErr := utils.TakeInLegacyBackup(filePath, func(data []byte) error {
+		return h.NetworkPathService.ValidateWorkbook(bytes.NewReader(data), opts)
+	})
+	backup, err := utils.GetLatestBackup(filePath)
+	if err != nil && legacyErr != nil {
+		err = fmt.Errorf("%w; %v", err, legacyErr)
+	}
+	if err == nil {
+		workbook, err := utils.ReadSource(backup.Path, 0)
+		if err != nil {
+			return orderSource{}, err
+		}
+		return orderSource{Workbook: workbook, Info: fallback(OriginBackup, backup.CreatedAt, time.Now(), sourceErr)}, nil
+	}
+	// The service serves the last stored import of a workbook it was not given
+	if snapshot, found := h.NetworkPathService.LatestSnapshot(filePath); found {
+		return orderSource{Workbook: models.Workbook{Path: filePath}, Info: fallback(OriginStore, snapshot.ImportedAt, time.Now(), sourceErr)}, nil
+	}
+	return orderSource{}, err
+}
+
+// cacheErrorStatus picks the response status of a failed cache call
+func cacheErrorStatus(err error) int {
+	if errors.Is(err, importexcel.ErrCacheDisabled) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// settingsUnavailable reports whether a failed settings call is down to the
+// settings workbook not being configured or reachable, and why
+func settingsUnavailable(err error) (string, bool) {
+	switch {
+	case errors.Is(err, importexcel.ErrSettingsNotConfigured):
+		return "not configured", true
+	case !errors.Is(err, importexcel.ErrSettingsUnreachable):
+		return "", false
+	case errors.Is(err, fs.ErrNotExist):
+		return "not found", true
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied", true
+	default:
+		return "unreachable", true
+	}
+}
+
+// snapshotErrorStatus picks the response status of a failed snapshot call
+func snapshotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, importexcel.ErrStoreDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, importexcel.ErrSnapshotNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// streamOrders writes each order as its own JSON line and flushes it right away.
+// Errors before the first line get a regular JSON error response; later ones
+// are reported as a final {"error": ...} line since the status is already sent.
+func (h *Handler) streamOrders(c *gin.Context, source orderSource, request importRequest) {
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+
+	start := time.Now()
+	defer func() {
+		source.Timings.since(PhaseParse, start)
+		source.Timings.report(c, request.Path)
+	}()
+	err := h.NetworkPathService.StreamOrders(source.Workbook, request.ImportOptions, request.RequestQuery, func(order models.PurchaseOrder) error {
+		if !started {
+			c.Header("Content-Type", MIMENDJSON)
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(order); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+
+	if err != nil {
+		if !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		_ = encoder.Encode(gin.H{"error": err.Error()})
+		c.Writer.Flush()
+		return
+	}
+
+	// An empty workbook still answers with an empty NDJSON body
+	if !started {
+		c.Header("Content-Type", MIMENDJSON)
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+// orderSource is where the orders of a request are read from
+type orderSource struct {
+	// Workbook holds the requested file or its latest backup, its cached
+	// orders instead of its data when it was unchanged, and neither when the
+	// orders come from the store
+	Workbook models.Workbook
+	// Info is reported to the caller along with the orders
+	Info SourceInfo
+	// Timings records the phases of the import so far
+	Timings *importTimings
+}
+
+// importRequest carries the source path, import options and query of an import call
+type importRequest struct {
+	Path string `json:"path" form:"path"`
+	models.ImportOptions
+	models.RequestQuery
+}
+
+// summaryRequest adds the grouping field to an import request
+type summaryRequest struct {
+	importRequest
+	GroupBy string `json:"groupBy" form:"groupBy"`
+}
+
+// exportRequest adds the file format and layout to an import request
+type exportRequest struct {
+	importRequest
+	Format    string   `json:"format" form:"format"`
+	Columns   []string `json:"columns" form:"columns"`
+	Delimiter string   `json:"delimiter" form:"delimiter"`
+	BOM       *bool    `json:"bom" form:"bom"`
+}
+
+// snapshotDiffRequest names the two snapshots to compare
+type snapshotDiffRequest struct {
+	From int64 `form:"from"`
+	To   int64 `form:"to"`
+}
+
+// exportOptions writes a byte order mark unless the request turns it off
+func (r exportRequest) exportOptions() orderexport.Options {
+	return orderexport.Options{
+		Format:    r.Format,
+		Columns:   r.Columns,
+		Delimiter: r.Delimiter,
+		BOM:       r.BOM == nil || *r.BOM,
+	}
+}
+
+// bindImportRequest reads the import request from the query string, falling back
+// to the JSON body when the query has no path. It writes a 400 response and
+// returns false when the request is unusable.
+func bindImportRequest(c *gin.Context) (importRequest, bool) {
+	var request importRequest
+	ok := bindRequest(c, &request, &request)
+	return request, ok
+}
+
+// bindRequest binds target the way bindImportRequest does and validates the
+// import request embedded in it.
+func bindRequest(c *gin.Context, target any, request *importRequest) bool {
+	// Try to get path and options from query parameters first
+	if err := c.ShouldBindQuery(target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return false
+	}
+
+	// If path is not in query, check the request body
+	if request.Path == "" && c.Request.Body != nil {
+		// Fields present in the body override those from the query. An empty
+		// body leaves them as they are.
+		if err := c.ShouldBindJSON(target); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return false
+		}
+	}
+
+	if request.Path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Path is required"})
+		return false
+	}
+
+	if err := orderquery.ValidateQuery(request.RequestQuery); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
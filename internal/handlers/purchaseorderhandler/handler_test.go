@@ -1,29 +1,77 @@
 package purchaseorderhandler
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"purchase-record/config"
 	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/importexcel"
+	"purchase-record/internal/purchaseorders/orderexport"
+	"purchase-record/internal/purchaseorders/orderwatch"
+	"purchase-record/internal/utils"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the backups made while resolving source paths out of the package
+	dir, err := os.MkdirTemp("", "purchaseorderhandler-backup")
+	if err != nil {
+		panic(err)
+	}
+	config.CF.Backup.Dir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // MockNetworkPathService is a mock implementation of INetworkPathService
 type MockNetworkPathService struct {
 	mock.Mock
 }
 
+// newMockNetworkPathService accepts every workbook that is backed up, so tests
+// only set up the calls they are about
+func newMockNetworkPathService() *MockNetworkPathService {
+	m := new(MockNetworkPathService)
+	m.On("ValidateWorkbook", mock.Anything, mock.Anything).Return(nil).Maybe()
+	m.On("CachedOrders", mock.Anything, mock.Anything).Return(nil, false).Maybe()
+	return m
+}
+
+// workbookAt matches a workbook read from path
+func workbookAt(path string) any {
+	return mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == path
+	})
+}
+
 // MockSettingPathService is a mock implementation of ISettingPathService
 type MockSettingPathService struct {
 	mock.Mock
 }
 
-func (m *MockSettingPathService) GetSettingPath(filePath string) ([]models.SettingExcelData, error) {
-	args := m.Called(filePath)
+func (m *MockSettingPathService) GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error) {
+	args := m.Called(filePath, sheet)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -38,6 +86,91 @@ func (m *MockNetworkPathService) GetOrdersFromPath(filePath string) ([]models.Pu
 	return args.Get(0).([]models.PurchaseOrder), args.Error(1)
 }
 
+func (m *MockNetworkPathService) GetOrdersFromPathWithOptions(filePath string, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	args := m.Called(filePath, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PurchaseOrder), args.Error(1)
+}
+
+func (m *MockNetworkPathService) QueryOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery) (models.OrderPage, error) {
+	args := m.Called(workbook, opts, query)
+	return args.Get(0).(models.OrderPage), args.Error(1)
+}
+
+func (m *MockNetworkPathService) StreamOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, fn func(models.PurchaseOrder) error) error {
+	args := m.Called(workbook, opts, query, fn)
+	return args.Error(0)
+}
+
+func (m *MockNetworkPathService) SummarizeOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, groupBy string) (models.OrderSummary, error) {
+	args := m.Called(workbook, opts, query, groupBy)
+	return args.Get(0).(models.OrderSummary), args.Error(1)
+}
+
+func (m *MockNetworkPathService) ImportOrdersFromReader(reader io.Reader, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	args := m.Called(reader, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PurchaseOrder), args.Error(1)
+}
+
+func (m *MockNetworkPathService) GetSheetsFromPath(filePath string) ([]models.SheetInfo, error) {
+	args := m.Called(filePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SheetInfo), args.Error(1)
+}
+
+func (m *MockNetworkPathService) ImportWorkbook(workbook models.Workbook, opts models.ImportOptions) (int64, error) {
+	args := m.Called(workbook, opts)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNetworkPathService) ValidateWorkbook(reader io.Reader, opts models.ImportOptions) error {
+	args := m.Called(reader, opts)
+	return args.Error(0)
+}
+
+func (m *MockNetworkPathService) CachedOrders(workbook models.Workbook, opts models.ImportOptions) ([]models.PurchaseOrder, bool) {
+	args := m.Called(workbook, opts)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1)
+	}
+	return args.Get(0).([]models.PurchaseOrder), args.Bool(1)
+}
+
+func (m *MockNetworkPathService) CacheStats() (models.CacheStats, error) {
+	args := m.Called()
+	return args.Get(0).(models.CacheStats), args.Error(1)
+}
+
+func (m *MockNetworkPathService) InvalidateCache(path string) (int, error) {
+	args := m.Called(path)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNetworkPathService) LatestSnapshot(sourcePath string) (models.StoredImport, bool) {
+	args := m.Called(sourcePath)
+	return args.Get(0).(models.StoredImport), args.Bool(1)
+}
+
+func (m *MockNetworkPathService) ListSnapshots(sourcePath string) ([]models.StoredImport, error) {
+	args := m.Called(sourcePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StoredImport), args.Error(1)
+}
+
+func (m *MockNetworkPathService) DiffSnapshots(fromID, toID int64) (models.SnapshotDiff, error) {
+	args := m.Called(fromID, toID)
+	return args.Get(0).(models.SnapshotDiff), args.Error(1)
+}
+
 func TestGetOrdersFromNetworkPath(t *testing.T) {
 	// Create a temporary test file
 	tempDir := t.TempDir()
@@ -56,14 +189,14 @@ func TestGetOrdersFromNetworkPath(t *testing.T) {
 		{
 			name: "successful read from original file",
 			setupMock: func(m *MockNetworkPathService) {
-				m.On("GetOrdersFromPath", mock.Anything).Return([]models.PurchaseOrder{}, nil)
+				m.On("QueryOrders", mock.Anything, mock.Anything, mock.Anything).Return(models.OrderPage{Orders: []models.PurchaseOrder{}}, nil)
 			},
 			expectedStatus: 200,
 		},
 		{
 			name: "service error",
 			setupMock: func(m *MockNetworkPathService) {
-				m.On("GetOrdersFromPath", mock.Anything).Return(nil, assert.AnError)
+				m.On("QueryOrders", mock.Anything, mock.Anything, mock.Anything).Return(models.OrderPage{}, assert.AnError)
 			},
 			expectedStatus: 500,
 			expectedError:  assert.AnError.Error(),
@@ -73,7 +206,7 @@ func TestGetOrdersFromNetworkPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create mock service
-			mockService := new(MockNetworkPathService)
+			mockService := newMockNetworkPathService()
 			mockSettingService := new(MockSettingPathService)
 			tt.setupMock(mockService)
 
@@ -113,35 +246,474 @@ func TestGetOrdersFromNetworkPath(t *testing.T) {
 	}
 }
 
+func TestGetOrdersFromNetworkPath_StoredOrders(t *testing.T) {
+	missingPath := filepath.Join(t.TempDir(), "missing.xlsx")
+
+	mockService := newMockNetworkPathService()
+	importedAt := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	mockService.On("LatestSnapshot", missingPath).Return(models.StoredImport{ID: 1, ImportedAt: importedAt}, true).Twice()
+	mockService.On("QueryOrders", workbookAt(missingPath), models.ImportOptions{}, mock.Anything).
+		Return(models.OrderPage{Orders: []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}}, Total: 1}, nil)
+	mockService.On("LatestSnapshot", missingPath).Return(models.StoredImport{}, false)
+
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(missingPath), nil)
+		handler.GetOrdersFromNetworkPath(c)
+		return w
+	}
+
+	// Neither the file nor a backup exists, but the service has stored orders
+	w := request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"job_id_no":"J-001"`)
+	var response PageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, OriginStore, response.Source.Origin)
+	assert.Equal(t, importedAt, *response.Source.BackupTime)
+	assert.InDelta(t, 7200, *response.Source.BackupAgeSeconds, 5)
+	assert.Contains(t, response.Source.SourceError, "missing.xlsx")
+	assert.Equal(t, OriginStore, w.Header().Get(HeaderDataOrigin))
+
+	// A stored import older than the limit is refused
+	handler.MaxStaleness = time.Hour
+	w = request()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "is older than 1h0m0s")
+	assert.Contains(t, w.Body.String(), `"sourceError"`)
+	handler.MaxStaleness = 0
+
+	// Without stored orders the request fails
+	w = request()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to find original file or backup")
+	mockService.AssertExpectations(t)
+}
+
+func TestGetOrdersFromNetworkPath_RejectedBackup(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(filePath, []byte("half saved"), 0644))
+
+	mockService := new(MockNetworkPathService)
+	mockService.On("CachedOrders", mock.Anything, mock.Anything).Return(nil, false)
+	mockService.On("ValidateWorkbook", mock.Anything, models.ImportOptions{}).Return(errors.New("failed to open Excel workbook: zip: not a valid zip file"))
+	mockService.On("QueryOrders", workbookAt(filePath), models.ImportOptions{}, mock.Anything).Return(models.OrderPage{Orders: []models.PurchaseOrder{}}, nil)
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(filePath), nil)
+	handler.GetOrdersFromNetworkPath(c)
+
+	// The orders are still served and the reason is reported
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response PageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	expected := "source file failed validation, the previous backup is kept: failed to open Excel workbook: zip: not a valid zip file"
+	assert.Equal(t, SourceInfo{Origin: OriginSource, BackupError: expected}, response.Source)
+	assert.Equal(t, expected, w.Header().Get(HeaderBackupError))
+
+	_, err := utils.GetLatestBackupFile(filePath)
+	assert.Error(t, err)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetOrdersFromNetworkPath_BackupOrigin(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(filePath, []byte("workbook"), 0644))
+
+	mockService := newMockNetworkPathService()
+	mockService.On("StreamOrders", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(filePath), nil)
+		c.Request.Header.Set("Accept", MIMENDJSON)
+		handler.GetOrdersFromNetworkPath(c)
+		return w
+	}
+
+	// Reading the source backs it up
+	w := request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, OriginSource, w.Header().Get(HeaderDataOrigin))
+	assert.Empty(t, w.Header().Get(HeaderBackupTime))
+
+	// Once the source is gone the backup is served and said to be one
+	require.NoError(t, os.Remove(filePath))
+	w = request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, OriginBackup, w.Header().Get(HeaderDataOrigin))
+	backupTime, err := time.Parse(time.RFC3339, w.Header().Get(HeaderBackupTime))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), backupTime, time.Minute)
+	assert.Equal(t, "0", w.Header().Get(HeaderBackupAge))
+	assert.Contains(t, w.Header().Get(HeaderSourceError), "orders.xlsx")
+
+	// The orders are stored under the original path
+	backup, err := utils.GetLatestBackup(filePath)
+	require.NoError(t, err)
+	mockService.AssertCalled(t, "StreamOrders", workbookAt(backup.Path), models.ImportOptions{Source: filePath}, mock.Anything, mock.Anything)
+}
+
+func TestGetOrdersFromNetworkPath_ReadOnce(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(filePath, []byte("workbook"), 0644))
+
+	// Validation, backup and parsing all get the bytes of the single read
+	mockService := new(MockNetworkPathService)
+	mockService.On("CachedOrders", mock.Anything, mock.Anything).Return(nil, false)
+	mockService.On("ValidateWorkbook", mock.Anything, models.ImportOptions{}).Run(func(args mock.Arguments) {
+		data, _ := io.ReadAll(args.Get(0).(io.Reader))
+		assert.Equal(t, "workbook", string(data))
+	}).Return(nil)
+	mockService.On("QueryOrders", mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == filePath && string(workbook.Data) == "workbook"
+	}), models.ImportOptions{}, mock.Anything).Return(models.OrderPage{Orders: []models.PurchaseOrder{}}, nil)
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(filePath), nil)
+		handler.GetOrdersFromNetworkPath(c)
+		return w
+	}
+
+	w := request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^read;dur=[\d.]+, backup;dur=[\d.]+, parse;dur=[\d.]+$`, w.Header().Get(HeaderServerTiming))
+	backup, err := utils.GetLatestBackup(filePath)
+	require.NoError(t, err)
+	data, err := os.ReadFile(backup.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "workbook", string(data))
+	mockService.AssertExpectations(t)
+
+	// A workbook over the size limit is neither read nor replaced by a backup
+	handler.MaxSourceSize = 4
+	w = request()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "source file is too large")
+	mockService.AssertNumberOfCalls(t, "QueryOrders", 1)
+}
+
+func TestGetOrdersFromNetworkPath_Cached(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(filePath, []byte("workbook"), 0644))
+
+	// A workbook the service has cached is passed on with its cached orders
+	// without being read
+	cached := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}}
+	mockService := new(MockNetworkPathService)
+	mockService.On("CachedOrders", mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == filePath && workbook.Size == 8 && !workbook.ModTime.IsZero()
+	}), models.ImportOptions{}).Return(cached, true)
+	mockService.On("QueryOrders", mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == filePath && !workbook.Loaded() && assert.ObjectsAreEqual(cached, workbook.Orders)
+	}), models.ImportOptions{}, mock.Anything).Return(models.OrderPage{Orders: []models.PurchaseOrder{}}, nil)
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(filePath), nil)
+	handler.GetOrdersFromNetworkPath(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hit", w.Header().Get(HeaderCache))
+	var response PageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, SourceInfo{Origin: OriginSource, Cached: true}, response.Source)
+
+	// Nor is it backed up again
+	_, err := utils.GetLatestBackup(filePath)
+	assert.Error(t, err)
+	mockService.AssertExpectations(t)
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		setupMock      func(*MockNetworkPathService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "stats",
+			method: "GET",
+			target: "/purchaseorders/cache",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("CacheStats").Return(models.CacheStats{Entries: 1, MaxEntries: 32, Hits: 5, Misses: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":{"entries":1,"maxEntries":32,"ttlSeconds":0,"hits":5,"misses":1,"evictions":0}}`,
+		},
+		{
+			name:   "invalidate one path",
+			method: "DELETE",
+			target: "/purchaseorders/cache?path=orders.xlsx",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("InvalidateCache", "orders.xlsx").Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"removed":1}`,
+		},
+		{
+			name:   "cache disabled",
+			method: "DELETE",
+			target: "/purchaseorders/cache",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("InvalidateCache", "").Return(0, importexcel.ErrCacheDisabled)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"error":"the cache of parsed workbooks is not enabled"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockNetworkPathService)
+			tt.setupMock(mockService)
+			handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.method == "GET" {
+				handler.GetCacheStats(c)
+			} else {
+				handler.InvalidateCache(c)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetWatchStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		watcher        orderwatch.IWatcher
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "not polled yet",
+			watcher:        &orderwatch.Watcher{Settings: "settings.xlsx", Interval: time.Minute},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":{"settings":"settings.xlsx","intervalSeconds":60,"checkedAt":null,"paths":[]}}`,
+		},
+		{
+			name:           "watcher disabled",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"error":"The workbook watcher is not enabled"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &Handler{NetworkPathService: new(MockNetworkPathService), SettingPathService: new(MockSettingPathService), Watcher: tt.watcher}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/purchaseorders/watch", nil)
+			handler.GetWatchStatus(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetOrdersFromNetworkPath_Concurrent(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(filePath, []byte("workbook"), 0644))
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mockService := new(MockNetworkPathService)
+	mockService.On("CachedOrders", mock.Anything, mock.Anything).Return(nil, false)
+	mockService.On("ValidateWorkbook", mock.Anything, models.ImportOptions{}).Run(func(mock.Arguments) {
+		// Hold the first load until every request has asked for the workbook
+		close(started)
+		<-release
+	}).Return(nil).Once()
+	mockService.On("QueryOrders", workbookAt(filePath), models.ImportOptions{}, mock.Anything).Return(models.OrderPage{Orders: []models.PurchaseOrder{}}, nil)
+	handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, 10)
+	request := func(i int) {
+		defer wg.Done()
+		recorders[i] = httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorders[i])
+		c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+url.QueryEscape(filePath), nil)
+		handler.GetOrdersFromNetworkPath(c)
+	}
+	wg.Add(len(recorders))
+	go request(0)
+	<-started
+	for i := 1; i < len(recorders); i++ {
+		go request(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// One load was shared by every request, each timing its own parse
+	for _, w := range recorders {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, OriginSource, w.Header().Get(HeaderDataOrigin))
+		assert.Contains(t, w.Header().Get(HeaderServerTiming), "parse;dur=")
+	}
+	mockService.AssertExpectations(t)
+	mockService.AssertNumberOfCalls(t, "CachedOrders", 1)
+	mockService.AssertNumberOfCalls(t, "QueryOrders", len(recorders))
+}
+
+func TestSnapshots(t *testing.T) {
+	snapshots := []models.StoredImport{{ID: 2, SourcePath: "orders.xlsx", RowCount: 3}, {ID: 1, SourcePath: "orders.xlsx", RowCount: 2}}
+	diff := models.SnapshotDiff{From: snapshots[1], To: snapshots[0], OrderDiff: models.OrderDiff{Unchanged: 2}}
+
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*MockNetworkPathService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "list snapshots of a path",
+			url:  "/purchaseorders/snapshots?path=orders.xlsx",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("ListSnapshots", "orders.xlsx").Return(snapshots, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"rowCount":3`,
+		},
+		{
+			name: "list without a store",
+			url:  "/purchaseorders/snapshots",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("ListSnapshots", "").Return(nil, importexcel.ErrStoreDisabled)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   importexcel.ErrStoreDisabled.Error(),
+		},
+		{
+			name: "diff two snapshots",
+			url:  "/purchaseorders/snapshots/diff?from=1&to=2",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("DiffSnapshots", int64(1), int64(2)).Return(diff, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"unchanged":2`,
+		},
+		{
+			name: "diff with an unknown snapshot",
+			url:  "/purchaseorders/snapshots/diff?from=1&to=9",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("DiffSnapshots", int64(1), int64(9)).Return(models.SnapshotDiff{}, fmt.Errorf("%w: 9", importexcel.ErrSnapshotNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "snapshot not found: 9",
+		},
+		{
+			name:           "diff without snapshot IDs",
+			url:            "/purchaseorders/snapshots/diff?from=1",
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Snapshot IDs 'from' and 'to' are required",
+		},
+		{
+			name:           "diff with invalid snapshot IDs",
+			url:            "/purchaseorders/snapshots/diff?from=one&to=2",
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid query parameters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			tt.setupMock(mockService)
+			handler := &Handler{NetworkPathService: mockService, SettingPathService: new(MockSettingPathService)}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.url, nil)
+			if strings.Contains(tt.url, "/diff") {
+				handler.DiffSnapshots(c)
+			} else {
+				handler.ListSnapshots(c)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestNewHandler(t *testing.T) {
 	handler := NewHandler()
 	assert.NotNil(t, handler)
 }
 
 func TestGetSettingPath(t *testing.T) {
+	configured := config.SettingsConfig{Path: "settings.xlsx", From: "flag -settings"}
 	tests := []struct {
 		name           string
+		settings       config.SettingsConfig
 		setupMock      func(*MockSettingPathService)
 		expectedStatus int
 		expectedError  string
+		expectedBody   string
 	}{
 		{
-			name: "successful retrieval",
+			name:     "successful retrieval",
+			settings: configured,
 			setupMock: func(m *MockSettingPathService) {
-				m.On("GetSettingPath", mock.Anything).Return([]models.SettingExcelData{
+				m.On("GetSettingPath", "settings.xlsx", mock.Anything).Return([]models.SettingExcelData{
 					{Path: "test/path", Name: "Test"},
 				}, nil)
 			},
 			expectedStatus: 200,
 		},
 		{
-			name: "service error",
+			name:     "service error",
+			settings: configured,
 			setupMock: func(m *MockSettingPathService) {
-				m.On("GetSettingPath", mock.Anything).Return(nil, assert.AnError)
+				m.On("GetSettingPath", "settings.xlsx", mock.Anything).Return(nil, assert.AnError)
 			},
 			expectedStatus: 500,
 			expectedError:  "Failed to get setting path: " + assert.AnError.Error(),
 		},
+		{
+			name: "not configured",
+			setupMock: func(m *MockSettingPathService) {
+				m.On("GetSettingPath", "", mock.Anything).Return(nil, importexcel.ErrSettingsNotConfigured)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: `{"error":"Settings workbook is unavailable: the settings workbook is not configured","reason":"not configured","path":"","from":"",` +
+				`"hint":"Set the PURCHASE_RECORD_SETTINGS environment variable or the -settings flag to the settings workbook path"}`,
+		},
+		{
+			name:     "unreachable",
+			settings: configured,
+			setupMock: func(m *MockSettingPathService) {
+				m.On("GetSettingPath", "settings.xlsx", mock.Anything).Return(nil, fmt.Errorf("%w: %w", importexcel.ErrSettingsUnreachable, fs.ErrNotExist))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"error":"Settings workbook is unavailable: the settings workbook is unreachable: file does not exist","reason":"not found","path":"settings.xlsx","from":"flag -settings"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -153,6 +725,7 @@ func TestGetSettingPath(t *testing.T) {
 			// Create handler with mock service
 			handler := &Handler{
 				SettingPathService: mockService,
+				Settings:           tt.settings,
 			}
 
 			// Create test context
@@ -168,9 +741,673 @@ func TestGetSettingPath(t *testing.T) {
 			if tt.expectedError != "" {
 				assert.Contains(t, w.Body.String(), tt.expectedError)
 			}
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
 
 			// Verify mock expectations
 			mockService.AssertExpectations(t)
 		})
 	}
 }
+
+func TestGetOrdersFromNetworkPath_SheetSelection(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilePath := filepath.Join(tempDir, "test.xlsx")
+	assert.NoError(t, os.WriteFile(testFilePath, []byte("test data"), 0644))
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedOpts   models.ImportOptions
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "sheet name from query",
+			target:         "/purchaseorders?sheet=PO+2024&path=" + testFilePath,
+			expectedOpts:   models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO 2024"}},
+			expectedStatus: 200,
+		},
+		{
+			name:           "sheet pattern and profile from body",
+			target:         "/purchaseorders",
+			body:           `{"path": "` + testFilePath + `", "profile": "variant.yaml", "sheetPattern": "^PO"}`,
+			expectedOpts:   models.ImportOptions{Profile: "variant.yaml", SheetSelector: models.SheetSelector{SheetPattern: "^PO"}},
+			expectedStatus: 200,
+		},
+		{
+			name:           "sheet index from query",
+			target:         "/purchaseorders?sheetIndex=2&path=" + testFilePath,
+			expectedOpts:   models.ImportOptions{SheetSelector: models.SheetSelector{SheetIndex: intPtr(2)}},
+			expectedStatus: 200,
+		},
+		{
+			name:           "invalid sheet index",
+			target:         "/purchaseorders?sheetIndex=second&path=" + testFilePath,
+			expectedStatus: 400,
+		},
+		{
+			name:           "date range of bare years",
+			target:         "/purchaseorders?prDate=2024..2025&path=" + testFilePath,
+			expectedStatus: 400,
+			expectedBody:   "'2024' is not a date in the form YYYY-MM-DD",
+		},
+		{
+			name:           "malformed body",
+			target:         "/purchaseorders",
+			body:           `{"path": "` + testFilePath + `",`,
+			expectedStatus: 400,
+			expectedBody:   "Invalid request body: unexpected EOF",
+		},
+		{
+			name:           "body of the wrong type",
+			target:         "/purchaseorders",
+			body:           `{"path": "` + testFilePath + `", "sheetIndex": "second"}`,
+			expectedStatus: 400,
+			expectedBody:   "Invalid request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			if tt.expectedStatus == 200 {
+				mockService.On("QueryOrders", workbookAt(testFilePath), tt.expectedOpts, models.RequestQuery{}).Return(models.OrderPage{}, nil)
+			}
+
+			handler := &Handler{NetworkPathService: mockService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.GetOrdersFromNetworkPath(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrdersFromNetworkPath_NDJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilePath := filepath.Join(tempDir, "test.xlsx")
+	assert.NoError(t, os.WriteFile(testFilePath, []byte("test data"), 0644))
+
+	emit := func(orders ...models.PurchaseOrder) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			fn := args.Get(3).(func(models.PurchaseOrder) error)
+			for _, order := range orders {
+				_ = fn(order)
+			}
+		}
+	}
+	first := models.PurchaseOrder{JobIDNo: stringPtr("J-001")}
+	second := models.PurchaseOrder{JobIDNo: stringPtr("J-002")}
+
+	tests := []struct {
+		name                string
+		setupMock           func(*MockNetworkPathService)
+		expectedStatus      int
+		expectedContentType string
+		expectedLines       []string
+	}{
+		{
+			name: "one order per line",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, mock.Anything).Run(emit(first, second)).Return(nil)
+			},
+			expectedStatus:      200,
+			expectedContentType: MIMENDJSON,
+			expectedLines:       []string{`"job_id_no":"J-001"`, `"job_id_no":"J-002"`},
+		},
+		{
+			name: "empty workbook",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, mock.Anything).Return(nil)
+			},
+			expectedStatus:      200,
+			expectedContentType: MIMENDJSON,
+		},
+		{
+			name: "error before the first order",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, mock.Anything).Return(assert.AnError)
+			},
+			expectedStatus:      500,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedLines:       []string{assert.AnError.Error()},
+		},
+		{
+			name: "error after the first order",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, mock.Anything).Run(emit(first)).Return(assert.AnError)
+			},
+			expectedStatus:      200,
+			expectedContentType: MIMENDJSON,
+			expectedLines:       []string{`"job_id_no":"J-001"`, `{"error":"` + assert.AnError.Error() + `"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			tt.setupMock(mockService)
+
+			handler := &Handler{NetworkPathService: mockService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/purchaseorders?path="+testFilePath, nil)
+			c.Request.Header.Set("Accept", MIMENDJSON)
+
+			handler.GetOrdersFromNetworkPath(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			body := strings.TrimSpace(w.Body.String())
+			if len(tt.expectedLines) == 0 {
+				assert.Empty(t, body)
+			}
+			if tt.expectedStatus == 200 && len(tt.expectedLines) > 0 {
+				lines := strings.Split(body, "\n")
+				assert.Len(t, lines, len(tt.expectedLines))
+				for i, expected := range tt.expectedLines {
+					assert.Contains(t, lines[i], expected)
+				}
+			} else {
+				for _, expected := range tt.expectedLines {
+					assert.Contains(t, body, expected)
+				}
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrdersFromNetworkPath_Pagination(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilePath := filepath.Join(tempDir, "test.xlsx")
+	assert.NoError(t, os.WriteFile(testFilePath, []byte("test data"), 0644))
+	escapedPath := url.QueryEscape(testFilePath)
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedQuery  models.RequestQuery
+		page           models.OrderPage
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:          "middle page from query",
+			target:        "/purchaseorders?path=" + escapedPath + "&search=acme&pageNo=2&pageSize=10",
+			expectedQuery: models.RequestQuery{Search: "acme", PageNo: 2, PageSize: 10},
+			page: models.OrderPage{
+				Orders:     []models.PurchaseOrder{{JobIDNo: stringPtr("J-011")}},
+				Total:      25,
+				PageNo:     2,
+				PageSize:   10,
+				TotalPages: 3,
+			},
+			expectedStatus: 200,
+			expectedBody: []string{
+				`"total":25`, `"pageNo":2`, `"pageSize":10`, `"totalPages":3`,
+				`"next":"/purchaseorders?pageNo=3\u0026pageSize=10\u0026path=` + escapedPath + `\u0026search=acme"`,
+				`"prev":"/purchaseorders?pageNo=1\u0026pageSize=10\u0026path=` + escapedPath + `\u0026search=acme"`,
+			},
+		},
+		{
+			name:          "last page from body has no next link",
+			target:        "/purchaseorders",
+			body:          `{"path": "` + testFilePath + `", "search": "acme", "pageNo": 3, "pageSize": 10}`,
+			expectedQuery: models.RequestQuery{Search: "acme", PageNo: 3, PageSize: 10},
+			page: models.OrderPage{
+				Orders:     []models.PurchaseOrder{},
+				Total:      25,
+				PageNo:     3,
+				PageSize:   10,
+				TotalPages: 3,
+			},
+			expectedStatus: 200,
+			expectedBody:   []string{`"links":{"self":"/purchaseorders?pageNo=3\u0026pageSize=10\u0026path=` + escapedPath + `\u0026search=acme","prev":`},
+		},
+		{
+			name:   "filters and sort are passed on and kept in links",
+			target: "/purchaseorders?path=" + escapedPath + "&customer=Acme&customer=Globex&ordered=10..&sort=-po_date&pageSize=10",
+			expectedQuery: models.RequestQuery{
+				PageSize:    10,
+				OrderFilter: models.OrderFilter{Customer: []string{"Acme", "Globex"}, Ordered: "10..", Sort: "-po_date"},
+			},
+			page: models.OrderPage{
+				Orders:     []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}},
+				Total:      12,
+				PageNo:     1,
+				PageSize:   10,
+				TotalPages: 2,
+			},
+			expectedStatus: 200,
+			expectedBody: []string{
+				`"next":"/purchaseorders?customer=Acme\u0026customer=Globex\u0026ordered=10..\u0026pageNo=2\u0026pageSize=10\u0026path=` + escapedPath + `\u0026sort=-po_date"`,
+			},
+		},
+		{
+			name:           "unknown sort field",
+			target:         "/purchaseorders?path=" + escapedPath + "&sort=price",
+			expectedStatus: 400,
+			expectedBody:   []string{`cannot sort by unknown field \"price\"`},
+		},
+		{
+			name:           "negative page size",
+			target:         "/purchaseorders?path=" + escapedPath + "&pageSize=-1",
+			expectedStatus: 400,
+			expectedBody:   []string{"pageSize must not be negative"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			if tt.expectedStatus == 200 {
+				mockService.On("QueryOrders", workbookAt(testFilePath), models.ImportOptions{}, tt.expectedQuery).Return(tt.page, nil)
+			}
+
+			handler := &Handler{NetworkPathService: mockService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.GetOrdersFromNetworkPath(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetSheets(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		setupMock      func(*MockNetworkPathService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "successful listing",
+			target: "/purchaseorders/sheets?path=test.xlsx",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("GetSheetsFromPath", "test.xlsx").Return([]models.SheetInfo{
+					{Index: 0, Name: "Setting", Rows: 2, Columns: 2, Visible: true},
+				}, nil)
+			},
+			expectedStatus: 200,
+			expectedBody:   `"name":"Setting"`,
+		},
+		{
+			name:           "missing path",
+			target:         "/purchaseorders/sheets",
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: 400,
+			expectedBody:   "Path is required",
+		},
+		{
+			name:   "service error",
+			target: "/purchaseorders/sheets?path=test.xlsx",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("GetSheetsFromPath", "test.xlsx").Return(nil, assert.AnError)
+			},
+			expectedStatus: 500,
+			expectedBody:   assert.AnError.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			tt.setupMock(mockService)
+
+			handler := &Handler{NetworkPathService: mockService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.target, nil)
+
+			handler.GetSheets(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetSummary(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilePath := filepath.Join(tempDir, "test.xlsx")
+	assert.NoError(t, os.WriteFile(testFilePath, []byte("test data"), 0644))
+	escapedPath := url.QueryEscape(testFilePath)
+
+	summary := models.OrderSummary{
+		GroupBy: "customer",
+		Groups:  []models.SummaryGroup{{Key: "Acme", Lines: 2, Ordered: 20, Received: 10, Remain: 10, CompletionPercent: 50}},
+		Total:   models.SummaryGroup{Lines: 2, Ordered: 20, Received: 10, Remain: 10, CompletionPercent: 50},
+	}
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		setupMock      func(*MockNetworkPathService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "group from query with filter",
+			target: "/purchaseorders/summary?path=" + escapedPath + "&groupBy=customer&status=Completed",
+			setupMock: func(m *MockNetworkPathService) {
+				query := models.RequestQuery{OrderFilter: models.OrderFilter{Status: []string{"Completed"}}}
+				m.On("SummarizeOrders", workbookAt(testFilePath), models.ImportOptions{}, query, "customer").Return(summary, nil)
+			},
+			expectedStatus: 200,
+			expectedBody:   `"groups":[{"key":"Acme","lines":2,"completedLines":0,"ordered":20,"received":10,"remain":10,"completionPercent":50}]`,
+		},
+		{
+			name:   "group from body",
+			target: "/purchaseorders/summary",
+			body:   `{"path": "` + testFilePath + `", "groupBy": "customer"}`,
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("SummarizeOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, "customer").Return(summary, nil)
+			},
+			expectedStatus: 200,
+			expectedBody:   `"groupBy":"customer"`,
+		},
+		{
+			name:           "unknown group",
+			target:         "/purchaseorders/summary?path=" + escapedPath + "&groupBy=product_code",
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: 400,
+			expectedBody:   "groupBy must be one of",
+		},
+		{
+			name:   "service error",
+			target: "/purchaseorders/summary?path=" + escapedPath + "&groupBy=status",
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("SummarizeOrders", workbookAt(testFilePath), models.ImportOptions{}, models.RequestQuery{}, "status").Return(models.OrderSummary{}, assert.AnError)
+			},
+			expectedStatus: 500,
+			expectedBody:   assert.AnError.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			tt.setupMock(mockService)
+
+			handler := &Handler{NetworkPathService: mockService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.GetSummary(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportOrders(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilePath := filepath.Join(tempDir, "test.xlsx")
+	assert.NoError(t, os.WriteFile(testFilePath, []byte("test data"), 0644))
+	target := "/purchaseorders/export?path=" + url.QueryEscape(testFilePath) + "&customer=Acme"
+	query := models.RequestQuery{OrderFilter: models.OrderFilter{Customer: []string{"Acme"}}}
+
+	t.Run("workbook download", func(t *testing.T) {
+		mockService := newMockNetworkPathService()
+		mockService.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, query, mock.Anything).Run(func(args mock.Arguments) {
+			fn := args.Get(3).(func(models.PurchaseOrder) error)
+			_ = fn(models.PurchaseOrder{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme")})
+			_ = fn(models.PurchaseOrder{JobIDNo: stringPtr("J-002"), Customer: stringPtr("Acme")})
+		}).Return(nil)
+
+		handler := &Handler{NetworkPathService: mockService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", target, nil)
+
+		handler.ExportOrders(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, orderexport.MIMEXLSX, w.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename="purchaseorders-\d{8}-\d{6}\.xlsx"$`, w.Header().Get("Content-Disposition"))
+
+		f, err := excelize.OpenReader(w.Body)
+		assert.NoError(t, err)
+		defer f.Close()
+		rows, err := f.GetRows(orderexport.SheetName)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 3)
+		assert.Equal(t, "J-002", rows[2][0])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("csv download", func(t *testing.T) {
+		mockService := newMockNetworkPathService()
+		mockService.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, query, mock.Anything).Run(func(args mock.Arguments) {
+			fn := args.Get(3).(func(models.PurchaseOrder) error)
+			_ = fn(models.PurchaseOrder{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme"), Ordered: intPtr(4)})
+		}).Return(nil)
+
+		handler := &Handler{NetworkPathService: mockService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", target+"&format=csv&columns=job_id_no,ordered&bom=false", nil)
+
+		handler.ExportOrders(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `.csv"`)
+		assert.Equal(t, "job_id_no,ordered\nJ-001,4\n", w.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("tsv download starts with a byte order mark", func(t *testing.T) {
+		mockService := newMockNetworkPathService()
+		mockService.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, query, mock.Anything).Return(nil)
+
+		handler := &Handler{NetworkPathService: mockService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", target+"&format=tsv&columns=job_id_no&columns=customer", nil)
+
+		handler.ExportOrders(c)
+
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "\ufeffjob_id_no\tcustomer\n", w.Body.String())
+	})
+
+	t.Run("invalid export options", func(t *testing.T) {
+		handler := &Handler{NetworkPathService: newMockNetworkPathService()}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", target+"&format=csv&columns=price", nil)
+
+		handler.ExportOrders(c)
+
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), `cannot export unknown column`)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		mockService := newMockNetworkPathService()
+		mockService.On("StreamOrders", workbookAt(testFilePath), models.ImportOptions{}, query, mock.Anything).Return(assert.AnError)
+
+		handler := &Handler{NetworkPathService: mockService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", target, nil)
+
+		handler.ExportOrders(c)
+
+		assert.Equal(t, 500, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.Contains(t, w.Body.String(), assert.AnError.Error())
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUploadOrders(t *testing.T) {
+	newUpload := func(t *testing.T, fields map[string]string, withFile bool) *http.Request {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for key, value := range fields {
+			assert.NoError(t, writer.WriteField(key, value))
+		}
+		if withFile {
+			part, err := writer.CreateFormFile("file", "orders.xlsx")
+			assert.NoError(t, err)
+			_, _ = part.Write([]byte("workbook bytes"))
+		}
+		assert.NoError(t, writer.Close())
+
+		request := httptest.NewRequest("POST", "/purchaseorders/upload", &body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		return request
+	}
+
+	warning := models.ImportWarning{Row: 5, Field: "pr_date", Value: "31/02/2024", Message: "'31/02/2024' is not a recognized date"}
+	orders := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-001")},
+		{JobIDNo: stringPtr("J-002"), Warnings: []models.ImportWarning{warning}},
+	}
+
+	tests := []struct {
+		name           string
+		request        func(t *testing.T) *http.Request
+		maxSourceSize  int64
+		setupMock      func(*MockNetworkPathService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "orders and warnings",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, map[string]string{"sheet": "PO 2024"}, true)
+			},
+			setupMock: func(m *MockNetworkPathService) {
+				opts := models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO 2024"}}
+				m.On("ImportOrdersFromReader", mock.Anything, opts).Return(orders, nil)
+			},
+			expectedStatus: 200,
+			expectedBody: []string{
+				`"fileName":"orders.xlsx"`, `"total":2`,
+				`"warnings":[{"row":5,"field":"pr_date","value":"31/02/2024","message":"'31/02/2024' is not a recognized date"}]}`,
+			},
+		},
+		{
+			name: "missing file",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, map[string]string{"sheet": "PO"}, false)
+			},
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: 400,
+			expectedBody:   []string{"An .xlsx file is required in the 'file' field"},
+		},
+		{
+			name: "unreadable workbook",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, nil, true)
+			},
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("ImportOrdersFromReader", mock.Anything, models.ImportOptions{}).Return(nil, assert.AnError)
+			},
+			expectedStatus: 400,
+			expectedBody:   []string{"Failed to import 'orders.xlsx': " + assert.AnError.Error()},
+		},
+		{
+			name: "file within the size limit",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, nil, true)
+			},
+			maxSourceSize: int64(len("workbook bytes")),
+			setupMock: func(m *MockNetworkPathService) {
+				m.On("ImportOrdersFromReader", mock.Anything, models.ImportOptions{}).Return(orders, nil)
+			},
+			expectedStatus: 200,
+			expectedBody:   []string{`"total":2`},
+		},
+		{
+			name: "file over the size limit",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, nil, true)
+			},
+			maxSourceSize:  int64(len("workbook bytes")) - 1,
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: 413,
+			expectedBody:   []string{"source file is too large: the uploaded workbook is over the limit of 13 bytes"},
+		},
+		{
+			name: "body over the size limit",
+			request: func(t *testing.T) *http.Request {
+				return newUpload(t, map[string]string{"sheet": strings.Repeat("x", 2*maxUploadOverhead)}, true)
+			},
+			maxSourceSize:  1 << 10,
+			setupMock:      func(m *MockNetworkPathService) {},
+			expectedStatus: 413,
+			expectedBody:   []string{"over the limit of 1024 bytes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := newMockNetworkPathService()
+			tt.setupMock(mockService)
+
+			handler := &Handler{NetworkPathService: mockService, MaxSourceSize: tt.maxSourceSize}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = tt.request(t)
+
+			handler.UploadOrders(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,177 @@
+package purchaseorderhandler
+
+import (
+	"net/url"
+	"purchase-record/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PageResponse is the envelope returned for a page of purchase orders
+type PageResponse struct {
+	Data       []models.PurchaseOrder `json:"data"`
+	Total      int64                  `json:"total"`
+	PageNo     int64                  `json:"pageNo"`
+	PageSize   int64                  `json:"pageSize"`
+	TotalPages int64                  `json:"totalPages"`
+	Links      PageLinks              `json:"links"`
+	Source     SourceInfo             `json:"source"`
+}
+
+// Origins of the orders in a response
+const (
+	// OriginSource is the requested file itself
+	OriginSource = "source"
+	// OriginBackup is the latest backup of an unreachable file
+	OriginBackup = "backup"
+	// OriginStore is the last stored import of an unreachable file
+	OriginStore = "store"
+)
+
+// SourceInfo tells where the orders of a response were read from
+type SourceInfo struct {
+	Origin string `json:"origin" example:"backup"`
+	// BackupTime is when the backup or stored import was made, set unless the
+	// origin is the source
+	BackupTime *time.Time `json:"backupTime,omitempty"`
+	// BackupAgeSeconds is how old the backup or stored import is
+	BackupAgeSeconds *int64 `json:"backupAgeSeconds,omitempty"`
+	// SourceError is why the requested file could not be read
+	SourceError string `json:"sourceError,omitempty"`
+	// BackupError is set when the source file could not be backed up, so the
+	// previous backup is kept
+	BackupError string `json:"backupError,omitempty"`
+	// Cached is set when the source file was unchanged since its orders were
+	// cached, so it was neither read nor backed up again
+	Cached bool `json:"cached,omitempty"`
+}
+
+// fallback describes orders read from a copy made at createdAt because the
+// source could not be read
+func fallback(origin string, createdAt, now time.Time, sourceErr error) SourceInfo {
+	createdAt = createdAt.UTC()
+	age := int64(now.Sub(createdAt) / time.Second)
+	return SourceInfo{
+		Origin:           origin,
+		BackupTime:       &createdAt,
+		BackupAgeSeconds: &age,
+		SourceError:      sourceErr.Error(),
+	}
+}
+
+// setHeaders repeats the source information as headers, for responses that
+// have no JSON envelope such as NDJSON and exports
+func (s SourceInfo) setHeaders(c *gin.Context) {
+	header := func(key, value string) {
+		if value != "" {
+			// Line breaks would end the header early
+			c.Header(key, strings.Join(strings.Fields(value), " "))
+		}
+	}
+	header(HeaderDataOrigin, s.Origin)
+	if s.BackupTime != nil {
+		header(HeaderBackupTime, s.BackupTime.Format(time.RFC3339))
+	}
+	if s.BackupAgeSeconds != nil {
+		header(HeaderBackupAge, strconv.FormatInt(*s.BackupAgeSeconds, 10))
+	}
+	header(HeaderSourceError, s.SourceError)
+	header(HeaderBackupError, s.BackupError)
+	if s.Cached {
+		header(HeaderCache, "hit")
+	}
+}
+
+// PageLinks point to neighbouring pages of the same query
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// UploadResponse lists the orders of an uploaded workbook together with every
+// warning raised while reading it
+type UploadResponse struct {
+	FileName string                 `json:"fileName"`
+	Data     []models.PurchaseOrder `json:"data"`
+	Total    int                    `json:"total"`
+	Warnings []models.ImportWarning `json:"warnings"`
+}
+
+func newUploadResponse(fileName string, orders []models.PurchaseOrder) UploadResponse {
+	warnings := []models.ImportWarning{}
+	for _, order := range orders {
+		warnings = append(warnings, order.Warnings...)
+	}
+	return UploadResponse{FileName: fileName, Data: orders, Total: len(orders), Warnings: warnings}
+}
+
+func newPageResponse(c *gin.Context, request importRequest, page models.OrderPage) PageResponse {
+	links := PageLinks{Self: pageLink(c, request, page.PageNo)}
+	if page.PageNo < page.TotalPages {
+		links.Next = pageLink(c, request, page.PageNo+1)
+	}
+	if page.PageNo > 1 {
+		links.Prev = pageLink(c, request, min(page.PageNo-1, max(page.TotalPages, 1)))
+	}
+
+	return PageResponse{
+		Data:       page.Orders,
+		Total:      page.Total,
+		PageNo:     page.PageNo,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages,
+		Links:      links,
+	}
+}
+
+// pageLink rebuilds the request as a query string, whether it arrived in the
+// query or the JSON body, so the link alone reproduces the same query
+func pageLink(c *gin.Context, request importRequest, pageNo int64) string {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	set("path", request.Path)
+	set("profile", request.Profile)
+	set("sheet", request.Sheet)
+	set("sheetPattern", request.SheetPattern)
+	if request.SheetIndex != nil {
+		set("sheetIndex", strconv.Itoa(*request.SheetIndex))
+	}
+	set("search", request.Search)
+	for key, list := range map[string][]string{
+		"salesTeam":      request.SalesTeam,
+		"projectManager": request.ProjectManager,
+		"purchasing":     request.Purchasing,
+		"customer":       request.Customer,
+		"status":         request.Status,
+		"type":           request.Type,
+	} {
+		for _, value := range list {
+			values.Add(key, value)
+		}
+	}
+	set("ordered", request.Ordered)
+	set("remain", request.Remain)
+	set("prDate", request.PRDate)
+	set("poDate", request.PODate)
+	set("requestDate", request.RequestDate)
+	set("poReceiveDate", request.POReceiveDate)
+	set("receivedDate", request.ReceivedDate)
+	set("stockPickingOutDate", request.StockPickingOutDate)
+	set("deliveryDate", request.DeliveryDate)
+	set("sort", request.Sort)
+	if request.PageSize > 0 {
+		set("pageSize", strconv.FormatInt(request.PageSize, 10))
+	}
+	set("pageNo", strconv.FormatInt(pageNo, 10))
+
+	return c.Request.URL.Path + "?" + values.Encode()
+}
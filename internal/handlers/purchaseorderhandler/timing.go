@@ -0,0 +1,72 @@
+package purchaseorderhandler
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderServerTiming lists how long each phase of an import took, such as
+// "read;dur=812.4, backup;dur=3.1, parse;dur=95.0" in milliseconds
+const HeaderServerTiming = "Server-Timing"
+
+// Phases of an import
+const (
+	// PhaseRead fetches the workbook, or its backup, into memory
+	PhaseRead = "read"
+	// PhaseFallback reads the latest backup, or looks up the stored import,
+	// of a workbook that could not be read
+	PhaseFallback = "fallback"
+	// PhaseBackup validates the workbook and writes it as a backup
+	PhaseBackup = "backup"
+	// PhaseParse turns the workbook into orders and applies the query
+	PhaseParse = "parse"
+)
+
+// importTimings records how long each phase of an import took
+type importTimings struct {
+	phases []importPhase
+}
+
+type importPhase struct {
+	name string
+	took time.Duration
+}
+
+// since records the time elapsed since start as the named phase
+func (t *importTimings) since(name string, start time.Time) {
+	t.add(name, time.Since(start))
+}
+
+// add records how long the named phase took
+func (t *importTimings) add(name string, took time.Duration) {
+	t.phases = append(t.phases, importPhase{name: name, took: took})
+}
+
+// clone copies the phases recorded so far, so a shared load can be timed
+// further by every request using it
+func (t *importTimings) clone() *importTimings {
+	return &importTimings{phases: slices.Clone(t.phases)}
+}
+
+// String formats the phases as a Server-Timing header value
+func (t *importTimings) String() string {
+	parts := make([]string, len(t.phases))
+	for i, phase := range t.phases {
+		parts[i] = fmt.Sprintf("%s;dur=%.1f", phase.name, float64(phase.took.Microseconds())/1000)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// report sends the phases recorded so far as a header, which only reaches the
+// client while the response has not started, and logs them
+func (t *importTimings) report(c *gin.Context, path string) {
+	if !c.Writer.Written() {
+		c.Header(HeaderServerTiming, t.String())
+	}
+	log.Printf("Imported '%s': %s", path, t)
+}
@@ -0,0 +1,12 @@
+package models
+
+// CacheStats reports the use of the cache of parsed workbooks since start
+type CacheStats struct {
+	Entries    int   `json:"entries"`
+	MaxEntries int   `json:"maxEntries"`
+	TTLSeconds int64 `json:"ttlSeconds"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	// Evictions counts entries dropped to make room, expired or invalidated
+	Evictions int64 `json:"evictions"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DateLayout is the ISO-8601 calendar date format used in JSON.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date without time of day, encoded as "YYYY-MM-DD".
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date part of t in UTC.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return err
+	}
+	*d = NewDate(t)
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+// ImportProfile describes the layout of a purchase order workbook.
+// The data sheet is chosen by SheetName, then SheetPattern, then SheetIndex.
+type ImportProfile struct {
+	Name         string          `json:"name" yaml:"name"`
+	SheetName    string          `json:"sheet_name,omitempty" yaml:"sheet_name,omitempty"`
+	SheetPattern string          `json:"sheet_pattern,omitempty" yaml:"sheet_pattern,omitempty"`
+	SheetIndex   int             `json:"sheet_index" yaml:"sheet_index"`
+	HeaderRows   int             `json:"header_rows" yaml:"header_rows"`
+	KeyField     string          `json:"key_field" yaml:"key_field"`
+	Columns      []ProfileColumn `json:"columns" yaml:"columns"`
+	StatusRule   StatusRule      `json:"status_rule" yaml:"status_rule"`
+}
+
+// ProfileColumn maps a PurchaseOrder field to a workbook column, either by
+// header text or by a fixed column letter.
+type ProfileColumn struct {
+	Field    string   `json:"field" yaml:"field"`
+	Headers  []string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Column   string   `json:"column,omitempty" yaml:"column,omitempty"`
+	Type     string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Required bool     `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// StatusRule describes how the Status field is derived for each row.
+type StatusRule struct {
+	Type          string `json:"type" yaml:"type"`
+	DeliveryField string `json:"delivery_field,omitempty" yaml:"delivery_field,omitempty"`
+	OrderedField  string `json:"ordered_field,omitempty" yaml:"ordered_field,omitempty"`
+	Completed     string `json:"completed,omitempty" yaml:"completed,omitempty"`
+	NotCompleted  string `json:"not_completed,omitempty" yaml:"not_completed,omitempty"`
+}
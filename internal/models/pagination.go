@@ -0,0 +1,10 @@
+package models
+
+// OrderPage is one page of purchase orders matching a RequestQuery.
+type OrderPage struct {
+	Orders     []PurchaseOrder
+	Total      int64
+	PageNo     int64
+	PageSize   int64
+	TotalPages int64
+}
@@ -1,27 +1,49 @@
 package models
 
 type PurchaseOrder struct {
-	JobIDNo             *string `json:"job_id_no"`
-	Type                *string `json:"type"`
-	SalesTeam           *string `json:"sales_team"`
-	ProjectManager      *string `json:"project_manager"`
-	Purchasing          *string `json:"purchasing"`
-	Customer            *string `json:"customer"`
-	ProductCode         *string `json:"product_code"`
-	ProductDescription  *string `json:"product_description"`
-	Ordered             *int    `json:"ordered"`
-	Received            *int    `json:"received"`
-	Remain              *int    `json:"remain"`
-	PR                  *string `json:"pr"`
-	PRDate              *string `json:"pr_date"`
-	PO                  *string `json:"po"`
-	PODate              *string `json:"po_date"`
-	RequestDate         *string `json:"request_date"`
-	POReceiveDate       *string `json:"po_receive_date"`
-	Distribution        *string `json:"distribution"`
-	ReceivedDate        *string `json:"received_date"`
-	StockPickingOutDate *string `json:"stock_picking_out_date"`
-	DeliveryDate        *string `json:"delivery_date"`
-	Status              *string `json:"status"`
-	Remark              *string `json:"remark"`
+	JobIDNo             *string         `json:"job_id_no"`
+	Type                *string         `json:"type"`
+	SalesTeam           *string         `json:"sales_team"`
+	ProjectManager      *string         `json:"project_manager"`
+	Purchasing          *string         `json:"purchasing"`
+	Customer            *string         `json:"customer"`
+	ProductCode         *string         `json:"product_code"`
+	ProductDescription  *string         `json:"product_description"`
+	Ordered             *int            `json:"ordered"`
+	Received            *int            `json:"received"`
+	Remain              *int            `json:"remain"`
+	PR                  *string         `json:"pr"`
+	PRDate              *Date           `json:"pr_date"`
+	PO                  *string         `json:"po"`
+	PODate              *Date           `json:"po_date"`
+	RequestDate         *Date           `json:"request_date"`
+	POReceiveDate       *Date           `json:"po_receive_date"`
+	Distribution        *string         `json:"distribution"`
+	ReceivedDate        *Date           `json:"received_date"`
+	StockPickingOutDate *Date           `json:"stock_picking_out_date"`
+	DeliveryLog         *string         `json:"delivery_log"`
+	DeliveryDate        *Date           `json:"delivery_date"`
+	Shipments           []Shipment      `json:"shipments,omitempty"`
+	Status              *string         `json:"status"`
+	StatusReason        *string         `json:"status_reason"`
+	Remark              *string         `json:"remark"`
+	Warnings            []ImportWarning `json:"warnings,omitempty"`
+}
+
+// Shipment is one entry of the delivery log, such as "12/03/24 (5 pcs)".
+// Date or Quantity is nil when that part of the entry is missing or unreadable.
+type Shipment struct {
+	Date     *Date  `json:"date"`
+	Quantity *int   `json:"quantity"`
+	Unit     string `json:"unit,omitempty"`
+	Raw      string `json:"raw"`
+}
+
+// ImportWarning reports a cell that could not be converted. The order keeps
+// the field empty and the original text is preserved here.
+type ImportWarning struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
 }
@@ -4,4 +4,58 @@ type RequestQuery struct {
 	Search   string `json:"search" form:"search"`
 	PageNo   int64  `json:"pageNo" form:"pageNo"`
 	PageSize int64  `json:"pageSize" form:"pageSize"`
+	OrderFilter
+}
+
+// OrderFilter narrows purchase orders by field value. Text fields match any of
+// the listed values, ignoring case. Ranges are written "min..max" with either
+// end optional, such as "10..", "..5" or "2024-01-01..2024-03-31"; a single
+// value matches exactly. Sort lists JSON field names separated by commas,
+// each prefixed with "-" for descending order, such as "customer,-po_date".
+type OrderFilter struct {
+	SalesTeam           []string `json:"salesTeam" form:"salesTeam"`
+	ProjectManager      []string `json:"projectManager" form:"projectManager"`
+	Purchasing          []string `json:"purchasing" form:"purchasing"`
+	Customer            []string `json:"customer" form:"customer"`
+	Status              []string `json:"status" form:"status"`
+	Type                []string `json:"type" form:"type"`
+	Ordered             string   `json:"ordered" form:"ordered"`
+	Remain              string   `json:"remain" form:"remain"`
+	PRDate              string   `json:"prDate" form:"prDate"`
+	PODate              string   `json:"poDate" form:"poDate"`
+	RequestDate         string   `json:"requestDate" form:"requestDate"`
+	POReceiveDate       string   `json:"poReceiveDate" form:"poReceiveDate"`
+	ReceivedDate        string   `json:"receivedDate" form:"receivedDate"`
+	StockPickingOutDate string   `json:"stockPickingOutDate" form:"stockPickingOutDate"`
+	DeliveryDate        string   `json:"deliveryDate" form:"deliveryDate"`
+	Sort                string   `json:"sort" form:"sort"`
+}
+
+// SheetSelector picks a worksheet by exact name, regular expression or
+// zero-based index, in that order of precedence.
+type SheetSelector struct {
+	Sheet        string `json:"sheet" form:"sheet"`
+	SheetPattern string `json:"sheetPattern" form:"sheetPattern"`
+	SheetIndex   *int   `json:"sheetIndex" form:"sheetIndex"`
+}
+
+// IsEmpty reports whether no selection was made.
+func (s SheetSelector) IsEmpty() bool {
+	return s.Sheet == "" && s.SheetPattern == "" && s.SheetIndex == nil
+}
+
+type ImportOptions struct {
+	Profile string `json:"profile" form:"profile"`
+	SheetSelector
+	// Source is the original location of the workbook when the file read is a
+	// copy of it, such as a backup. Stored imports are recorded under it.
+	Source string `json:"-" form:"-"`
+}
+
+// SourcePath returns the location imports of filePath are recorded under
+func (o ImportOptions) SourcePath(filePath string) string {
+	if o.Source != "" {
+		return o.Source
+	}
+	return filePath
 }
@@ -1,6 +1,14 @@
 package models
 
 type SettingExcelData struct {
-	Path string `json:"path"`
-	Name string `json:"name"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	Sheet string `json:"sheet,omitempty"`
+}
+
+// ImportOptions returns the options a request for the workbook at Path sends
+// when it follows the settings: the data sheet by name when one is given, the
+// default profile otherwise. Imports with them share the request's cache key.
+func (s SettingExcelData) ImportOptions() ImportOptions {
+	return ImportOptions{SheetSelector: SheetSelector{Sheet: s.Sheet}}
 }
@@ -0,0 +1,9 @@
+package models
+
+type SheetInfo struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Rows    int    `json:"rows"`
+	Columns int    `json:"columns"`
+	Visible bool   `json:"visible"`
+}
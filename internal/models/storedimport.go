@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// StoredImport describes one import of a workbook saved in the order store,
+// a snapshot of the orders the workbook held at that time.
+type StoredImport struct {
+	ID          int64     `json:"id"`
+	SourcePath  string    `json:"sourcePath"`
+	ImportedAt  time.Time `json:"importedAt"`
+	RowCount    int       `json:"rowCount"`
+	ContentHash string    `json:"contentHash"`
+}
+
+// SnapshotDiff compares the orders of two stored imports.
+type SnapshotDiff struct {
+	From StoredImport `json:"from"`
+	To   StoredImport `json:"to"`
+	OrderDiff
+}
+
+// OrderDiff lists the orders added, removed and changed between two sets of
+// orders. Orders are matched by job ID and product code.
+type OrderDiff struct {
+	Added     []PurchaseOrder `json:"added"`
+	Removed   []PurchaseOrder `json:"removed"`
+	Changed   []OrderChange   `json:"changed"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// OrderChange is an order found in both sets with different field values.
+// Order is the newer version.
+type OrderChange struct {
+	Key    string        `json:"key"`
+	Order  PurchaseOrder `json:"order"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is one field of an order that differs between the two sets.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// OrderSummary totals purchase orders per value of the GroupBy field.
+type OrderSummary struct {
+	GroupBy string         `json:"groupBy"`
+	Groups  []SummaryGroup `json:"groups"`
+	Total   SummaryGroup   `json:"total"`
+}
+
+// SummaryGroup holds the totals of the orders sharing one Key. Lines without a
+// value for the grouped field have an empty Key. CompletedLines counts lines
+// whose received quantity reached the ordered one and CompletionPercent is the
+// received quantity as a share of the ordered quantity.
+type SummaryGroup struct {
+	Key               string  `json:"key"`
+	Lines             int64   `json:"lines"`
+	CompletedLines    int64   `json:"completedLines"`
+	Ordered           int64   `json:"ordered"`
+	Received          int64   `json:"received"`
+	Remain            int64   `json:"remain"`
+	CompletionPercent float64 `json:"completionPercent"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WatchStatus reports the workbook watcher's last pass over the paths listed
+// in the settings workbook
+type WatchStatus struct {
+	Settings        string     `json:"settings"`
+	IntervalSeconds int64      `json:"intervalSeconds"`
+	CheckedAt       *time.Time `json:"checkedAt"`
+	// Error is why the settings workbook could not be read on the last pass,
+	// in which case the paths of the pass before are kept
+	Error string        `json:"error,omitempty"`
+	Paths []WatchedPath `json:"paths"`
+}
+
+// WatchedPath is the last import of one path listed in the settings workbook
+type WatchedPath struct {
+	Path      string     `json:"path"`
+	Name      string     `json:"name"`
+	Sheet     string     `json:"sheet,omitempty"`
+	CheckedAt *time.Time `json:"checkedAt"`
+	// ImportedAt is when the workbook was last re-imported after a change
+	ImportedAt *time.Time `json:"importedAt"`
+	// Size and ModTime identify the version last imported
+	Size        int64      `json:"size"`
+	ModTime     *time.Time `json:"modTime"`
+	ContentHash string     `json:"contentHash,omitempty"`
+	RowCount    int64      `json:"rowCount"`
+	Imports     int64      `json:"imports"`
+	// Error is why the last check failed; the path is imported again on the
+	// next check
+	Error       string `json:"error,omitempty"`
+	BackupError string `json:"backupError,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// Workbook is an Excel file read into memory in one pass, so the same bytes
+// are backed up, hashed and parsed without reading a network share twice
+type Workbook struct {
+	// Path is where Data was read from, the source file or one of its backups
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// Hash is the hex-encoded SHA-256 of Data
+	Hash string
+	// Data is nil when the file could not be read; services with an order
+	// store then serve the latest stored import of the source
+	Data []byte
+	// Orders holds the orders already parsed from the file when they were
+	// found in the cache, in which case Data is not read. They are shared and
+	// must not be modified.
+	Orders []PurchaseOrder
+}
+
+// Loaded reports whether the workbook's content was read
+func (w Workbook) Loaded() bool {
+	return w.Data != nil
+}
+
+// Reader returns a reader over the workbook's content
+func (w Workbook) Reader() io.Reader {
+	return bytes.NewReader(w.Data)
+}
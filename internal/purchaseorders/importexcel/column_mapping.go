@@ -0,0 +1,235 @@
+package importexcel
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Field names identify PurchaseOrder fields and match their JSON tags.
+const (
+	FieldJobIDNo             = "job_id_no"
+	FieldType                = "type"
+	FieldSalesTeam           = "sales_team"
+	FieldProjectManager      = "project_manager"
+	FieldPurchasing          = "purchasing"
+	FieldCustomer            = "customer"
+	FieldProductCode         = "product_code"
+	FieldProductDescription  = "product_description"
+	FieldOrdered             = "ordered"
+	FieldReceived            = "received"
+	FieldRemain              = "remain"
+	FieldPR                  = "pr"
+	FieldPRDate              = "pr_date"
+	FieldPO                  = "po"
+	FieldPODate              = "po_date"
+	FieldRequestDate         = "request_date"
+	FieldPOReceiveDate       = "po_receive_date"
+	FieldDistribution        = "distribution"
+	FieldReceivedDate        = "received_date"
+	FieldStockPickingOutDate = "stock_picking_out_date"
+	FieldDeliveryLog         = "delivery_log"
+	FieldDeliveryDate        = "delivery_date"
+	FieldStatus              = "status"
+	FieldRemark              = "remark"
+)
+
+// Coercions convert a cell's text into the value stored on the order.
+const (
+	CoerceString = "string"
+	CoerceTrim   = "trim"
+	CoerceInt    = "int"
+	CoerceNumber = "number"
+	CoerceDate   = "date"
+	// CoerceDateLog keeps the latest date of a cell listing several entries,
+	// such as "12/03/24 (5), 20/03/24 (3)"
+	CoerceDateLog = "date_log"
+)
+
+// stringFields returns the address of each string field of an order.
+var stringFields = map[string]func(*models.PurchaseOrder) **string{
+	FieldJobIDNo:            func(o *models.PurchaseOrder) **string { return &o.JobIDNo },
+	FieldType:               func(o *models.PurchaseOrder) **string { return &o.Type },
+	FieldSalesTeam:          func(o *models.PurchaseOrder) **string { return &o.SalesTeam },
+	FieldProjectManager:     func(o *models.PurchaseOrder) **string { return &o.ProjectManager },
+	FieldPurchasing:         func(o *models.PurchaseOrder) **string { return &o.Purchasing },
+	FieldCustomer:           func(o *models.PurchaseOrder) **string { return &o.Customer },
+	FieldProductCode:        func(o *models.PurchaseOrder) **string { return &o.ProductCode },
+	FieldProductDescription: func(o *models.PurchaseOrder) **string { return &o.ProductDescription },
+	FieldPR:                 func(o *models.PurchaseOrder) **string { return &o.PR },
+	FieldPO:                 func(o *models.PurchaseOrder) **string { return &o.PO },
+	FieldDistribution:       func(o *models.PurchaseOrder) **string { return &o.Distribution },
+	FieldDeliveryLog:        func(o *models.PurchaseOrder) **string { return &o.DeliveryLog },
+	FieldStatus:             func(o *models.PurchaseOrder) **string { return &o.Status },
+	FieldRemark:             func(o *models.PurchaseOrder) **string { return &o.Remark },
+}
+
+// intFields returns the address of each integer field of an order.
+var intFields = map[string]func(*models.PurchaseOrder) **int{
+	FieldOrdered:  func(o *models.PurchaseOrder) **int { return &o.Ordered },
+	FieldReceived: func(o *models.PurchaseOrder) **int { return &o.Received },
+	FieldRemain:   func(o *models.PurchaseOrder) **int { return &o.Remain },
+}
+
+// dateFields returns the address of each date field of an order.
+var dateFields = map[string]func(*models.PurchaseOrder) **models.Date{
+	FieldPRDate:              func(o *models.PurchaseOrder) **models.Date { return &o.PRDate },
+	FieldPODate:              func(o *models.PurchaseOrder) **models.Date { return &o.PODate },
+	FieldRequestDate:         func(o *models.PurchaseOrder) **models.Date { return &o.RequestDate },
+	FieldPOReceiveDate:       func(o *models.PurchaseOrder) **models.Date { return &o.POReceiveDate },
+	FieldReceivedDate:        func(o *models.PurchaseOrder) **models.Date { return &o.ReceivedDate },
+	FieldStockPickingOutDate: func(o *models.PurchaseOrder) **models.Date { return &o.StockPickingOutDate },
+	FieldDeliveryDate:        func(o *models.PurchaseOrder) **models.Date { return &o.DeliveryDate },
+}
+
+// columnMap maps a field name to its zero-based column index.
+type columnMap map[string]int
+
+// buildColumnMap locates every profile column in the header rows. Columns
+// with a fixed letter are placed directly; the others are matched by header
+// text, and when several columns match the same field the leftmost one wins.
+// Missing required fields are reported together in a single error.
+func buildColumnMap(headerRows [][]string, profileColumns []models.ProfileColumn) (columnMap, error) {
+	columns := make(columnMap, len(profileColumns))
+	aliasToFields := make(map[string][]string)
+	for _, pc := range profileColumns {
+		if pc.Column != "" {
+			col, err := excelize.ColumnNameToNumber(pc.Column)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column %q for field %q: %w", pc.Column, pc.Field, err)
+			}
+			columns[pc.Field] = col - 1
+			continue
+		}
+		for _, header := range pc.Headers {
+			alias := normalizeHeader(header)
+			aliasToFields[alias] = append(aliasToFields[alias], pc.Field)
+		}
+	}
+
+	width := 0
+	for _, row := range headerRows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for col := 0; col < width; col++ {
+		for _, row := range headerRows {
+			if col >= len(row) {
+				continue
+			}
+			// The same column may feed several fields
+			for _, field := range aliasToFields[normalizeHeader(row[col])] {
+				if _, taken := columns[field]; !taken {
+					columns[field] = col
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, pc := range profileColumns {
+		if !pc.Required {
+			continue
+		}
+		if _, ok := columns[pc.Field]; ok {
+			continue
+		}
+		name := pc.Field
+		if len(pc.Headers) > 0 {
+			name = pc.Headers[0]
+		}
+		missing = append(missing, fmt.Sprintf("%q", name))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required headers: %s", strings.Join(missing, ", "))
+	}
+
+	return columns, nil
+}
+
+// value returns the cell for field in row, or "" when the field is unmapped
+// or the row is shorter than the column index.
+func (m columnMap) value(row []string, field string) string {
+	col, ok := m[field]
+	if !ok || col >= len(row) {
+		return ""
+	}
+	return row[col]
+}
+
+// setField stores the coerced cell value on the matching order field. An
+// error is returned when a non-empty date cell cannot be parsed; the field is
+// then left empty.
+func setField(order *models.PurchaseOrder, pc models.ProfileColumn, value string) error {
+	if field, ok := stringFields[pc.Field]; ok {
+		if pc.Type == CoerceTrim {
+			value = strings.TrimSpace(value)
+		}
+		*field(order) = utils.StringOrNil(value)
+		return nil
+	}
+	if field, ok := intFields[pc.Field]; ok {
+		if pc.Type == CoerceNumber {
+			*field(order) = numberOrNil(value)
+			return nil
+		}
+		*field(order) = utils.IntOrNil(value)
+		return nil
+	}
+	if field, ok := dateFields[pc.Field]; ok && strings.TrimSpace(value) != "" {
+		parse := utils.ParseDate
+		if pc.Type == CoerceDateLog {
+			parse = latestLoggedDate
+		}
+		date, err := parse(value)
+		if err != nil {
+			return err
+		}
+		*field(order) = &date
+	}
+	return nil
+}
+
+// latestLoggedDate returns the most recent date among the entries of a log
+// cell, ignoring the parenthesized quantities.
+func latestLoggedDate(cell string) (models.Date, error) {
+	shipments, _ := utils.ParseDeliveryLog(cell)
+	latest := utils.LastShipmentDate(shipments)
+	if latest == nil {
+		return models.Date{}, fmt.Errorf("no recognized date in '%s'", cell)
+	}
+	return *latest, nil
+}
+
+// numberOrNil parses numbers written with thousands separators or decimals,
+// such as "1,200" or "8.00", rounding to the nearest integer.
+func numberOrNil(s string) *int {
+	s = strings.NewReplacer(",", "", " ", "").Replace(s)
+	if s == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return utils.IntPtrFromInt(int(math.Round(value)))
+}
+
+// normalizeHeader lowercases s and drops everything but letters and digits so
+// that "PO No.", "po no" and "PO_NO" compare equal.
+func normalizeHeader(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
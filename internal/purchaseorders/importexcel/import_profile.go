@@ -0,0 +1,186 @@
+package importexcel
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"purchase-record/internal/models"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Status rule types
+const (
+	StatusRuleDeliveryStatus = "delivery_status"
+	StatusRuleDeliveredUnits = "delivered_units"
+	StatusRuleColumn         = "column"
+)
+
+//go:embed profiles/default.yaml
+var defaultProfileData []byte
+
+// defaultProfile is parsed once from the embedded default.yaml.
+var defaultProfile = mustParseImportProfile(defaultProfileData, ".yaml")
+
+// DefaultImportProfile returns a copy of the built-in profile describing the
+// purchasing workbook.
+func DefaultImportProfile() *models.ImportProfile {
+	return cloneImportProfile(defaultProfile)
+}
+
+// cloneImportProfile copies a profile so callers can adjust it freely.
+func cloneImportProfile(profile *models.ImportProfile) *models.ImportProfile {
+	clone := *profile
+	clone.Columns = append([]models.ProfileColumn(nil), profile.Columns...)
+	return &clone
+}
+
+// LoadImportProfile reads a profile from a .yaml, .yml or .json file.
+func LoadImportProfile(profilePath string) (*models.ImportProfile, error) {
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import profile '%s': %w", profilePath, err)
+	}
+
+	profile, err := parseImportProfile(data, filepath.Ext(profilePath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid import profile '%s': %w", profilePath, err)
+	}
+	return profile, nil
+}
+
+func mustParseImportProfile(data []byte, ext string) *models.ImportProfile {
+	profile, err := parseImportProfile(data, ext)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in import profile: %v", err))
+	}
+	return profile
+}
+
+// parseImportProfile decodes data according to the file extension, fills in
+// defaults and validates the result.
+func parseImportProfile(data []byte, ext string) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	switch strings.ToLower(ext) {
+	case ".json":
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &profile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported profile format %q, expected .yaml, .yml or .json", ext)
+	}
+
+	if err := normalizeImportProfile(&profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// normalizeImportProfile applies defaults for omitted settings and rejects
+// unknown fields, coercions and status rules.
+func normalizeImportProfile(profile *models.ImportProfile) error {
+	if profile.SheetIndex < 0 {
+		return fmt.Errorf("sheet_index must not be negative")
+	}
+	if profile.SheetPattern != "" {
+		if _, err := regexp.Compile(profile.SheetPattern); err != nil {
+			return fmt.Errorf("invalid sheet_pattern: %w", err)
+		}
+	}
+	if profile.HeaderRows < 0 {
+		return fmt.Errorf("header_rows must not be negative")
+	}
+	if len(profile.Columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+
+	mapped := make(map[string]bool, len(profile.Columns))
+	for i := range profile.Columns {
+		pc := &profile.Columns[i]
+		if mapped[pc.Field] {
+			return fmt.Errorf("field %q is mapped more than once", pc.Field)
+		}
+		mapped[pc.Field] = true
+
+		if pc.Column == "" && len(pc.Headers) == 0 {
+			return fmt.Errorf("field %q needs headers or a column", pc.Field)
+		}
+
+		switch {
+		case stringFields[pc.Field] != nil:
+			if pc.Type == "" {
+				pc.Type = CoerceString
+			}
+			if pc.Type != CoerceString && pc.Type != CoerceTrim {
+				return fmt.Errorf("field %q does not support type %q", pc.Field, pc.Type)
+			}
+		case intFields[pc.Field] != nil:
+			if pc.Type == "" {
+				pc.Type = CoerceInt
+			}
+			if pc.Type != CoerceInt && pc.Type != CoerceNumber {
+				return fmt.Errorf("field %q does not support type %q", pc.Field, pc.Type)
+			}
+		case dateFields[pc.Field] != nil:
+			if pc.Type == "" {
+				pc.Type = CoerceDate
+			}
+			if pc.Type != CoerceDate && pc.Type != CoerceDateLog {
+				return fmt.Errorf("field %q does not support type %q", pc.Field, pc.Type)
+			}
+		default:
+			return fmt.Errorf("unknown field %q", pc.Field)
+		}
+	}
+
+	if profile.KeyField == "" {
+		profile.KeyField = FieldJobIDNo
+	}
+	if !mapped[profile.KeyField] {
+		return fmt.Errorf("key_field %q is not mapped to a column", profile.KeyField)
+	}
+
+	rule := &profile.StatusRule
+	if rule.Type == "" {
+		rule.Type = StatusRuleDeliveryStatus
+	}
+	switch rule.Type {
+	case StatusRuleDeliveryStatus, StatusRuleDeliveredUnits:
+		if rule.DeliveryField == "" {
+			rule.DeliveryField = FieldDeliveryLog
+		}
+		if rule.OrderedField == "" {
+			rule.OrderedField = FieldOrdered
+		}
+		if rule.Type == StatusRuleDeliveredUnits {
+			if rule.Completed == "" {
+				rule.Completed = "Completed"
+			}
+			if rule.NotCompleted == "" {
+				rule.NotCompleted = "Not Completed"
+			}
+		}
+		if stringFields[rule.DeliveryField] == nil || !mapped[rule.DeliveryField] {
+			return fmt.Errorf("status_rule delivery_field %q must be a mapped text field", rule.DeliveryField)
+		}
+		if intFields[rule.OrderedField] == nil || !mapped[rule.OrderedField] {
+			return fmt.Errorf("status_rule ordered_field %q must be a mapped quantity field", rule.OrderedField)
+		}
+	case StatusRuleColumn:
+		if !mapped[FieldStatus] {
+			return fmt.Errorf("status_rule %q requires the %q field to be mapped", rule.Type, FieldStatus)
+		}
+	default:
+		return fmt.Errorf("unknown status_rule type %q", rule.Type)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	io "io"
 	models "purchase-record/internal/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,6 +14,60 @@ type INetworkPathRepository struct {
 	mock.Mock
 }
 
+// CheckWorkbook provides a mock function with given fields: reader, profile
+func (_m *INetworkPathRepository) CheckWorkbook(reader io.Reader, profile *models.ImportProfile) error {
+	ret := _m.Called(reader, profile)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckWorkbook")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Reader, *models.ImportProfile) error); ok {
+		r0 = rf(reader, profile)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachOrder provides a mock function with given fields: filePath, profile, fn
+func (_m *INetworkPathRepository) EachOrder(filePath string, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	ret := _m.Called(filePath, profile, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *models.ImportProfile, func(models.PurchaseOrder) error) error); ok {
+		r0 = rf(filePath, profile, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachOrderFromReader provides a mock function with given fields: reader, profile, fn
+func (_m *INetworkPathRepository) EachOrderFromReader(reader io.Reader, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	ret := _m.Called(reader, profile, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachOrderFromReader")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Reader, *models.ImportProfile, func(models.PurchaseOrder) error) error); ok {
+		r0 = rf(reader, profile, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetOrdersFromNetworkPath provides a mock function with given fields: filePath
 func (_m *INetworkPathRepository) GetOrdersFromNetworkPath(filePath string) ([]models.PurchaseOrder, error) {
 	ret := _m.Called(filePath)
@@ -43,6 +98,96 @@ func (_m *INetworkPathRepository) GetOrdersFromNetworkPath(filePath string) ([]m
 	return r0, r1
 }
 
+// GetOrdersWithProfile provides a mock function with given fields: filePath, profile
+func (_m *INetworkPathRepository) GetOrdersWithProfile(filePath string, profile *models.ImportProfile) ([]models.PurchaseOrder, error) {
+	ret := _m.Called(filePath, profile)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersWithProfile")
+	}
+
+	var r0 []models.PurchaseOrder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, *models.ImportProfile) ([]models.PurchaseOrder, error)); ok {
+		return rf(filePath, profile)
+	}
+	if rf, ok := ret.Get(0).(func(string, *models.ImportProfile) []models.PurchaseOrder); ok {
+		r0 = rf(filePath, profile)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PurchaseOrder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, *models.ImportProfile) error); ok {
+		r1 = rf(filePath, profile)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSheets provides a mock function with given fields: filePath
+func (_m *INetworkPathRepository) GetSheets(filePath string) ([]models.SheetInfo, error) {
+	ret := _m.Called(filePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSheets")
+	}
+
+	var r0 []models.SheetInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.SheetInfo, error)); ok {
+		return rf(filePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.SheetInfo); ok {
+		r0 = rf(filePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SheetInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(filePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoadProfile provides a mock function with given fields: profilePath
+func (_m *INetworkPathRepository) LoadProfile(profilePath string) (*models.ImportProfile, error) {
+	ret := _m.Called(profilePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoadProfile")
+	}
+
+	var r0 *models.ImportProfile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.ImportProfile, error)); ok {
+		return rf(profilePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.ImportProfile); ok {
+		r0 = rf(profilePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImportProfile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(profilePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewINetworkPathRepository creates a new instance of INetworkPathRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewINetworkPathRepository(t interface {
@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	io "io"
 	models "purchase-record/internal/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,6 +14,92 @@ type INetworkPathService struct {
 	mock.Mock
 }
 
+// CacheStats provides a mock function with no fields
+func (_m *INetworkPathService) CacheStats() (models.CacheStats, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CacheStats")
+	}
+
+	var r0 models.CacheStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (models.CacheStats, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() models.CacheStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(models.CacheStats)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CachedOrders provides a mock function with given fields: workbook, opts
+func (_m *INetworkPathService) CachedOrders(workbook models.Workbook, opts models.ImportOptions) ([]models.PurchaseOrder, bool) {
+	ret := _m.Called(workbook, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CachedOrders")
+	}
+
+	var r0 []models.PurchaseOrder
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions) ([]models.PurchaseOrder, bool)); ok {
+		return rf(workbook, opts)
+	}
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions) []models.PurchaseOrder); ok {
+		r0 = rf(workbook, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PurchaseOrder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Workbook, models.ImportOptions) bool); ok {
+		r1 = rf(workbook, opts)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// DiffSnapshots provides a mock function with given fields: fromID, toID
+func (_m *INetworkPathService) DiffSnapshots(fromID int64, toID int64) (models.SnapshotDiff, error) {
+	ret := _m.Called(fromID, toID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DiffSnapshots")
+	}
+
+	var r0 models.SnapshotDiff
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int64) (models.SnapshotDiff, error)); ok {
+		return rf(fromID, toID)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int64) models.SnapshotDiff); ok {
+		r0 = rf(fromID, toID)
+	} else {
+		r0 = ret.Get(0).(models.SnapshotDiff)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int64) error); ok {
+		r1 = rf(fromID, toID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOrdersFromPath provides a mock function with given fields: filePath
 func (_m *INetworkPathService) GetOrdersFromPath(filePath string) ([]models.PurchaseOrder, error) {
 	ret := _m.Called(filePath)
@@ -43,6 +130,302 @@ func (_m *INetworkPathService) GetOrdersFromPath(filePath string) ([]models.Purc
 	return r0, r1
 }
 
+// GetOrdersFromPathWithOptions provides a mock function with given fields: filePath, opts
+func (_m *INetworkPathService) GetOrdersFromPathWithOptions(filePath string, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	ret := _m.Called(filePath, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersFromPathWithOptions")
+	}
+
+	var r0 []models.PurchaseOrder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, models.ImportOptions) ([]models.PurchaseOrder, error)); ok {
+		return rf(filePath, opts)
+	}
+	if rf, ok := ret.Get(0).(func(string, models.ImportOptions) []models.PurchaseOrder); ok {
+		r0 = rf(filePath, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PurchaseOrder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, models.ImportOptions) error); ok {
+		r1 = rf(filePath, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSheetsFromPath provides a mock function with given fields: filePath
+func (_m *INetworkPathService) GetSheetsFromPath(filePath string) ([]models.SheetInfo, error) {
+	ret := _m.Called(filePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSheetsFromPath")
+	}
+
+	var r0 []models.SheetInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.SheetInfo, error)); ok {
+		return rf(filePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.SheetInfo); ok {
+		r0 = rf(filePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SheetInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(filePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportOrdersFromReader provides a mock function with given fields: reader, opts
+func (_m *INetworkPathService) ImportOrdersFromReader(reader io.Reader, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	ret := _m.Called(reader, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportOrdersFromReader")
+	}
+
+	var r0 []models.PurchaseOrder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(io.Reader, models.ImportOptions) ([]models.PurchaseOrder, error)); ok {
+		return rf(reader, opts)
+	}
+	if rf, ok := ret.Get(0).(func(io.Reader, models.ImportOptions) []models.PurchaseOrder); ok {
+		r0 = rf(reader, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PurchaseOrder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(io.Reader, models.ImportOptions) error); ok {
+		r1 = rf(reader, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportWorkbook provides a mock function with given fields: workbook, opts
+func (_m *INetworkPathService) ImportWorkbook(workbook models.Workbook, opts models.ImportOptions) (int64, error) {
+	ret := _m.Called(workbook, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportWorkbook")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions) (int64, error)); ok {
+		return rf(workbook, opts)
+	}
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions) int64); ok {
+		r0 = rf(workbook, opts)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Workbook, models.ImportOptions) error); ok {
+		r1 = rf(workbook, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InvalidateCache provides a mock function with given fields: path
+func (_m *INetworkPathService) InvalidateCache(path string) (int, error) {
+	ret := _m.Called(path)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InvalidateCache")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(path)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(path)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(path)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LatestSnapshot provides a mock function with given fields: sourcePath
+func (_m *INetworkPathService) LatestSnapshot(sourcePath string) (models.StoredImport, bool) {
+	ret := _m.Called(sourcePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LatestSnapshot")
+	}
+
+	var r0 models.StoredImport
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (models.StoredImport, bool)); ok {
+		return rf(sourcePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) models.StoredImport); ok {
+		r0 = rf(sourcePath)
+	} else {
+		r0 = ret.Get(0).(models.StoredImport)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(sourcePath)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// ListSnapshots provides a mock function with given fields: sourcePath
+func (_m *INetworkPathService) ListSnapshots(sourcePath string) ([]models.StoredImport, error) {
+	ret := _m.Called(sourcePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSnapshots")
+	}
+
+	var r0 []models.StoredImport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.StoredImport, error)); ok {
+		return rf(sourcePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.StoredImport); ok {
+		r0 = rf(sourcePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StoredImport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sourcePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryOrders provides a mock function with given fields: workbook, opts, query
+func (_m *INetworkPathService) QueryOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery) (models.OrderPage, error) {
+	ret := _m.Called(workbook, opts, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryOrders")
+	}
+
+	var r0 models.OrderPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions, models.RequestQuery) (models.OrderPage, error)); ok {
+		return rf(workbook, opts, query)
+	}
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions, models.RequestQuery) models.OrderPage); ok {
+		r0 = rf(workbook, opts, query)
+	} else {
+		r0 = ret.Get(0).(models.OrderPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Workbook, models.ImportOptions, models.RequestQuery) error); ok {
+		r1 = rf(workbook, opts, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamOrders provides a mock function with given fields: workbook, opts, query, fn
+func (_m *INetworkPathService) StreamOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, fn func(models.PurchaseOrder) error) error {
+	ret := _m.Called(workbook, opts, query, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamOrders")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions, models.RequestQuery, func(models.PurchaseOrder) error) error); ok {
+		r0 = rf(workbook, opts, query, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SummarizeOrders provides a mock function with given fields: workbook, opts, query, groupBy
+func (_m *INetworkPathService) SummarizeOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, groupBy string) (models.OrderSummary, error) {
+	ret := _m.Called(workbook, opts, query, groupBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SummarizeOrders")
+	}
+
+	var r0 models.OrderSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions, models.RequestQuery, string) (models.OrderSummary, error)); ok {
+		return rf(workbook, opts, query, groupBy)
+	}
+	if rf, ok := ret.Get(0).(func(models.Workbook, models.ImportOptions, models.RequestQuery, string) models.OrderSummary); ok {
+		r0 = rf(workbook, opts, query, groupBy)
+	} else {
+		r0 = ret.Get(0).(models.OrderSummary)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Workbook, models.ImportOptions, models.RequestQuery, string) error); ok {
+		r1 = rf(workbook, opts, query, groupBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ValidateWorkbook provides a mock function with given fields: reader, opts
+func (_m *INetworkPathService) ValidateWorkbook(reader io.Reader, opts models.ImportOptions) error {
+	ret := _m.Called(reader, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateWorkbook")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Reader, models.ImportOptions) error); ok {
+		r0 = rf(reader, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewINetworkPathService creates a new instance of INetworkPathService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewINetworkPathService(t interface {
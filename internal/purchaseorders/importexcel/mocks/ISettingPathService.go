@@ -0,0 +1,58 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "purchase-record/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ISettingPathService is an autogenerated mock type for the ISettingPathService type
+type ISettingPathService struct {
+	mock.Mock
+}
+
+// GetSettingPath provides a mock function with given fields: filePath, sheet
+func (_m *ISettingPathService) GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error) {
+	ret := _m.Called(filePath, sheet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSettingPath")
+	}
+
+	var r0 []models.SettingExcelData
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, models.SheetSelector) ([]models.SettingExcelData, error)); ok {
+		return rf(filePath, sheet)
+	}
+	if rf, ok := ret.Get(0).(func(string, models.SheetSelector) []models.SettingExcelData); ok {
+		r0 = rf(filePath, sheet)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SettingExcelData)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, models.SheetSelector) error); ok {
+		r1 = rf(filePath, sheet)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewISettingPathService creates a new instance of ISettingPathService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewISettingPathService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ISettingPathService {
+	mock := &ISettingPathService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
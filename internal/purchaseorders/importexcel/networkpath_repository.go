@@ -2,7 +2,9 @@ package importexcel
 
 import (
 	"fmt"
+	"io"
 	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderstatus"
 	"purchase-record/internal/purchaseorders/utils"
 	"regexp"
 	"strconv"
@@ -13,105 +15,280 @@ import (
 // Pre-compile regex for better performance
 var unitRegex = regexp.MustCompile(`\((\d+)[^\)]*\)`)
 
+// Worksheets whose XML exceeds this size are streamed from a temporary file
+const streamingXMLSizeLimit = 4 << 20
+
 type INetworkPathRepository interface {
 	GetOrdersFromNetworkPath(filePath string) ([]models.PurchaseOrder, error)
+	GetOrdersWithProfile(filePath string, profile *models.ImportProfile) ([]models.PurchaseOrder, error)
+	EachOrder(filePath string, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error
+	EachOrderFromReader(reader io.Reader, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error
+	CheckWorkbook(reader io.Reader, profile *models.ImportProfile) error
+	LoadProfile(profilePath string) (*models.ImportProfile, error)
+	GetSheets(filePath string) ([]models.SheetInfo, error)
 }
 
-type NetworkPathRepository struct{}
+type NetworkPathRepository struct {
+	// Profile describes the workbook layout used when no profile is given
+	Profile *models.ImportProfile
+	// StatusEngine decides the status of profiles using the delivery_status
+	// rule; the default rules apply when it is nil
+	StatusEngine orderstatus.IStatusEngine
+}
 
 func NewNetworkPathRepository() INetworkPathRepository {
-	return &NetworkPathRepository{}
+	return &NetworkPathRepository{
+		Profile:      DefaultImportProfile(),
+		StatusEngine: orderstatus.NewStatusEngine(),
+	}
 }
 
 func (r *NetworkPathRepository) GetOrdersFromNetworkPath(filePath string) ([]models.PurchaseOrder, error) {
-	// Open the Excel file directly from the network path
+	return r.GetOrdersWithProfile(filePath, nil)
+}
+
+// LoadProfile reads an import profile file so it can be passed to GetOrdersWithProfile.
+// An empty path returns a copy of the repository's default profile.
+func (r *NetworkPathRepository) LoadProfile(profilePath string) (*models.ImportProfile, error) {
+	if profilePath == "" {
+		return cloneImportProfile(r.Profile), nil
+	}
+	return LoadImportProfile(profilePath)
+}
+
+// GetSheets lists the sheets of a workbook with their row and column counts
+func (r *NetworkPathRepository) GetSheets(filePath string) ([]models.SheetInfo, error) {
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Excel file at path '%s': %w", filePath, err)
 	}
 	defer f.Close()
 
-	// Get all sheets
-	sheets := f.GetSheetList()
-	if len(sheets) == 0 {
-		return nil, fmt.Errorf("no sheets found in Excel file")
+	return listSheets(f)
+}
+
+// GetOrdersWithProfile reads orders using the given layout, or the repository's
+// default profile when profile is nil
+func (r *NetworkPathRepository) GetOrdersWithProfile(filePath string, profile *models.ImportProfile) ([]models.PurchaseOrder, error) {
+	orders := []models.PurchaseOrder{}
+	err := r.EachOrder(filePath, profile, func(order models.PurchaseOrder) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return orders, nil
+}
 
-	// Use second sheet (index 1)
-	if len(sheets) <= 1 {
-		return nil, fmt.Errorf("sheet at index 1 not found in Excel file")
+// EachOrder streams the data sheet row by row and calls fn for every order as
+// soon as it is parsed, so memory stays bounded regardless of the sheet size.
+// Iteration stops at the first error returned by fn.
+func (r *NetworkPathRepository) EachOrder(filePath string, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	// Open the Excel file directly from the network path. Large sheets are
+	// unpacked to temporary files and read from there instead of memory.
+	f, err := excelize.OpenFile(filePath, excelize.Options{UnzipXMLSizeLimit: streamingXMLSizeLimit})
+	if err != nil {
+		return fmt.Errorf("failed to open Excel file at path '%s': %w", filePath, err)
 	}
-	sheetName := sheets[1]
+	defer f.Close()
+
+	return r.eachOrder(f, profile, fn)
+}
 
-	rows, err := f.GetRows(sheetName)
+// EachOrderFromReader works like EachOrder on a workbook read from reader,
+// such as an uploaded file.
+func (r *NetworkPathRepository) EachOrderFromReader(reader io.Reader, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	f, err := excelize.OpenReader(reader, excelize.Options{UnzipXMLSizeLimit: streamingXMLSizeLimit})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read rows from sheet '%s': %w", sheetName, err)
+		return fmt.Errorf("failed to open Excel workbook: %w", err)
 	}
+	defer f.Close()
 
-	// Pre-allocate slice with estimated capacity to reduce reallocations
-	estimatedCapacity := len(rows) - 3 // Subtract header rows
-	if estimatedCapacity < 0 {
-		estimatedCapacity = 0
+	return r.eachOrder(f, profile, fn)
+}
+
+// CheckWorkbook confirms that the workbook in reader opens and that its data
+// sheet has the header rows of the profile, or the default profile when it is
+// nil. Data rows are not read.
+func (r *NetworkPathRepository) CheckWorkbook(reader io.Reader, profile *models.ImportProfile) error {
+	if profile == nil {
+		profile = r.Profile
+	}
+
+	f, err := excelize.OpenReader(reader, excelize.Options{UnzipXMLSizeLimit: streamingXMLSizeLimit})
+	if err != nil {
+		return fmt.Errorf("failed to open Excel workbook: %w", err)
+	}
+	defer f.Close()
+
+	sheetName, err := selectSheet(f, profileSheetSelector(profile), profile.SheetIndex)
+	if err != nil {
+		return err
 	}
-	orders := make([]models.PurchaseOrder, 0, estimatedCapacity)
 
-	// Required number of columns
-	const requiredColumns = 58
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to read rows from sheet '%s': %w", sheetName, err)
+	}
+	defer rows.Close()
 
-	for i, row := range rows {
-		if i < 3 { // Skip header rows
+	headerRows := make([][]string, 0, profile.HeaderRows)
+	for len(headerRows) < profile.HeaderRows && rows.Next() {
+		row, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return fmt.Errorf("failed to read row %d from sheet '%s': %w", len(headerRows)+1, sheetName, err)
+		}
+		headerRows = append(headerRows, row)
+	}
+	if err := rows.Error(); err != nil {
+		return fmt.Errorf("failed to read rows from sheet '%s': %w", sheetName, err)
+	}
+	if len(headerRows) == 0 {
+		return fmt.Errorf("sheet '%s' is empty", sheetName)
+	}
+
+	if _, err := buildColumnMap(headerRows, profile.Columns); err != nil {
+		return fmt.Errorf("invalid header in sheet '%s': %w", sheetName, err)
+	}
+	return nil
+}
+
+// eachOrder reads the orders of an open workbook
+func (r *NetworkPathRepository) eachOrder(f *excelize.File, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	if profile == nil {
+		profile = r.Profile
+	}
+
+	// Pick the data sheet by name, pattern or index
+	sheetName, err := selectSheet(f, profileSheetSelector(profile), profile.SheetIndex)
+	if err != nil {
+		return err
+	}
+
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to read rows from sheet '%s': %w", sheetName, err)
+	}
+	defer rows.Close()
+
+	engine := r.StatusEngine
+	if engine == nil {
+		engine = orderstatus.NewStatusEngine()
+	}
+
+	var columns columnMap
+	headerRows := make([][]string, 0, profile.HeaderRows)
+	rowNumber := 0
+	for rows.Next() {
+		// Excel row numbers are one-based
+		rowNumber++
+
+		// Raw values keep date cells as Excel serials instead of locale formatted text
+		row, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return fmt.Errorf("failed to read row %d from sheet '%s': %w", rowNumber, sheetName, err)
+		}
+
+		if rowNumber <= profile.HeaderRows {
+			headerRows = append(headerRows, row)
 			continue
 		}
 
+		// Locate each field by its header text once the header rows are read
+		if columns == nil {
+			if columns, err = buildColumnMap(headerRows, profile.Columns); err != nil {
+				return fmt.Errorf("invalid header in sheet '%s': %w", sheetName, err)
+			}
+		}
+
 		// Early exit for empty row
-		if len(row) == 0 || row[0] == "" {
+		if columns.value(row, profile.KeyField) == "" {
 			continue
 		}
 
-		// Efficiently ensure row has enough columns
-		if len(row) < requiredColumns {
-			// Create new slice with required capacity and copy existing data
-			newRow := make([]string, requiredColumns)
-			copy(newRow, row)
-			row = newRow
+		if err := fn(buildOrder(row, rowNumber, columns, profile, engine)); err != nil {
+			return err
 		}
+	}
+	if err := rows.Error(); err != nil {
+		return fmt.Errorf("failed to read rows from sheet '%s': %w", sheetName, err)
+	}
 
-		// Pre-extract values that are used multiple times
-		deliveryDateValue := row[52]
-		orderedValue := row[12]
-
-		// Calculate status once and reuse
-		calculatedStatus := determineCompletionStatusOptimized(deliveryDateValue, orderedValue)
-
-		order := models.PurchaseOrder{
-			JobIDNo:             utils.StringOrNil(row[0]),
-			Type:                utils.StringOrNil(row[1]),
-			SalesTeam:           utils.StringOrNil(row[2]),
-			ProjectManager:      utils.StringOrNil(row[3]),
-			Purchasing:          utils.StringOrNil(row[4]),
-			Customer:            utils.StringOrNil(row[9]),
-			ProductCode:         utils.StringOrNil(row[10]),
-			ProductDescription:  utils.StringOrNil(row[11]),
-			Ordered:             utils.IntOrNil(orderedValue),
-			Received:            utils.IntOrNil(row[13]),
-			Remain:              utils.IntOrNil(row[14]),
-			PR:                  utils.StringOrNil(row[25]),
-			PRDate:              utils.StringOrNil(row[26]),
-			PO:                  utils.StringOrNil(row[27]),
-			PODate:              utils.StringOrNil(row[28]),
-			RequestDate:         utils.StringOrNil(row[29]),
-			POReceiveDate:       utils.StringOrNil(row[30]),
-			Distribution:        utils.StringOrNil(row[32]),
-			ReceivedDate:        utils.StringOrNil(row[36]),
-			StockPickingOutDate: utils.StringOrNil(deliveryDateValue),
-			Status:              &calculatedStatus,
-			Remark:              utils.StringOrNil(row[57]),
+	// A sheet holding only headers must still carry the required ones
+	if columns == nil && rowNumber > 0 {
+		if _, err := buildColumnMap(headerRows, profile.Columns); err != nil {
+			return fmt.Errorf("invalid header in sheet '%s': %w", sheetName, err)
 		}
+	}
 
-		orders = append(orders, order)
+	return nil
+}
+
+// buildOrder converts one data row into an order according to the profile.
+// Cells that cannot be converted are reported as warnings on the order.
+func buildOrder(row []string, rowNumber int, columns columnMap, profile *models.ImportProfile, engine orderstatus.IStatusEngine) models.PurchaseOrder {
+	var order models.PurchaseOrder
+	for _, pc := range profile.Columns {
+		value := columns.value(row, pc.Field)
+		if err := setField(&order, pc, value); err != nil {
+			order.Warnings = append(order.Warnings, models.ImportWarning{
+				Row:     rowNumber,
+				Field:   pc.Field,
+				Value:   value,
+				Message: err.Error(),
+			})
+		}
 	}
 
-	return orders, nil
+	// Split the delivery log into shipments, the last of which dates the delivery
+	if order.DeliveryLog != nil {
+		shipments, fragments := utils.ParseDeliveryLog(*order.DeliveryLog)
+		order.Shipments = shipments
+		if order.DeliveryDate == nil {
+			order.DeliveryDate = utils.LastShipmentDate(shipments)
+		}
+		for _, fragment := range fragments {
+			order.Warnings = append(order.Warnings, models.ImportWarning{
+				Row:     rowNumber,
+				Field:   FieldDeliveryLog,
+				Value:   fragment,
+				Message: fmt.Sprintf("'%s' is not a recognized delivery entry", fragment),
+			})
+		}
+	}
+
+	rule := profile.StatusRule
+	if rule.Type == StatusRuleColumn {
+		return order
+	}
+
+	// Calculate status from the delivery log and the parsed ordered quantity
+	deliveryLog := ""
+	if value := *stringFields[rule.DeliveryField](&order); value != nil {
+		deliveryLog = *value
+	}
+	orderedQty := *intFields[rule.OrderedField](&order)
+
+	switch rule.Type {
+	case StatusRuleDeliveredUnits:
+		calculatedStatus := rule.NotCompleted
+		if determineCompletionStatusOptimized(deliveryLog, orderedQty) {
+			calculatedStatus = rule.Completed
+		}
+		order.Status = &calculatedStatus
+	case StatusRuleDeliveryStatus:
+		shipments := order.Shipments
+		if rule.DeliveryField != FieldDeliveryLog {
+			shipments, _ = utils.ParseDeliveryLog(deliveryLog)
+		}
+		delivered := utils.ShippedQuantity(shipments)
+		result := engine.Evaluate(orderstatus.Facts{Order: order, Ordered: orderedQty, Delivered: delivered})
+		order.Status = &result.Status
+		order.StatusReason = &result.Reason
+	}
+
+	return order
 }
 
 // calculateTotalUnitsInDeliveryDateOptimized uses pre-compiled regex for better performance
@@ -138,25 +315,18 @@ func calculateTotalUnitsInDeliveryDateOptimized(deliveryDate string) (int, error
 	return totalUnits, nil
 }
 
-// determineCompletionStatusOptimized optimized version using pre-compiled regex
-func determineCompletionStatusOptimized(deliveryDateCell string, orderedCell string) string {
+// determineCompletionStatusOptimized reports whether the units listed in the
+// delivery cell add up to the ordered quantity
+func determineCompletionStatusOptimized(deliveryDateCell string, orderedQty *int) bool {
 	// Early returns for empty values
-	if deliveryDateCell == "" || orderedCell == "" {
-		return "Not Completed"
-	}
-
-	orderedQty, err := strconv.Atoi(orderedCell)
-	if err != nil || orderedQty == 0 {
-		return "Not Completed"
+	if deliveryDateCell == "" || orderedQty == nil || *orderedQty == 0 {
+		return false
 	}
 
 	totalUnitsInDelivery, err := calculateTotalUnitsInDeliveryDateOptimized(deliveryDateCell)
 	if err != nil {
-		return "Not Completed"
+		return false
 	}
 
-	if totalUnitsInDelivery == orderedQty {
-		return "Completed"
-	}
-	return "Not Completed"
+	return totalUnitsInDelivery == *orderedQty
 }
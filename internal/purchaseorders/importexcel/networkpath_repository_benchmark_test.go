@@ -0,0 +1,138 @@
+package importexcel
+
+import (
+	"fmt"
+	"path/filepath"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderstatus"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeLargeWorkbook generates a purchasing workbook with rowCount data rows
+// using excelize's stream writer so that generation itself stays cheap.
+func writeLargeWorkbook(tb testing.TB, rowCount int) string {
+	tb.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if _, err := f.NewSheet("PO"); err != nil {
+		tb.Fatalf("Failed to create sheet: %v", err)
+	}
+	sw, err := f.NewStreamWriter("PO")
+	if err != nil {
+		tb.Fatalf("Failed to create stream writer: %v", err)
+	}
+
+	header := []interface{}{
+		"Job ID No", "Type", "Sales Team", "Project Manager", "Purchasing", "Customer",
+		"Product Code", "Product Description", "Ordered", "Received", "Remain", "PR",
+		"PR Date", "PO", "PO Date", "Request Date", "PO Receive Date", "Distribution",
+		"Received Date", "Stock Picking Out Date", "Remark",
+	}
+	rows := [][]interface{}{{"Purchase Order Tracking"}, {}, header}
+	for i := 0; i < rowCount; i++ {
+		rows = append(rows, []interface{}{
+			fmt.Sprintf("J-%06d", i), "Standard", "Team A", "John", "Jane", fmt.Sprintf("Customer %d", i%50),
+			fmt.Sprintf("PROD%04d", i%1000), "Test Product", 10, 5, 5, fmt.Sprintf("PR%06d", i),
+			"01/01/2024", fmt.Sprintf("PO%06d", i), "15/01/2024", "01/02/2024", "20/01/2024", "Dept1",
+			"25/01/2024", "12/03/24 (5)\n20/03/24 (5)", "",
+		})
+	}
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := sw.SetRow(cell, row); err != nil {
+			tb.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		tb.Fatalf("Failed to flush stream writer: %v", err)
+	}
+
+	filePath := filepath.Join(tb.TempDir(), "large.xlsx")
+	if err := f.SaveAs(filePath); err != nil {
+		tb.Fatalf("Failed to save workbook: %v", err)
+	}
+	return filePath
+}
+
+// getOrdersWithGetRows is the previous implementation, which loads the whole
+// sheet with GetRows before building any order. It is kept for comparison.
+func getOrdersWithGetRows(filePath string, profile *models.ImportProfile) ([]models.PurchaseOrder, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheetName, err := selectSheet(f, profileSheetSelector(profile), profile.SheetIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+
+	headerRows := rows[:min(profile.HeaderRows, len(rows))]
+	columns, err := buildColumnMap(headerRows, profile.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]models.PurchaseOrder, 0, len(rows)-len(headerRows))
+	for i, row := range rows[len(headerRows):] {
+		if columns.value(row, profile.KeyField) == "" {
+			continue
+		}
+		orders = append(orders, buildOrder(row, len(headerRows)+i+1, columns, profile, orderstatus.NewStatusEngine()))
+	}
+	return orders, nil
+}
+
+func TestEachOrderMatchesGetRows(t *testing.T) {
+	filePath := writeLargeWorkbook(t, 200)
+	repo := &NetworkPathRepository{Profile: DefaultImportProfile()}
+
+	expected, err := getOrdersWithGetRows(filePath, repo.Profile)
+	assert.NoError(t, err)
+
+	orders, err := repo.GetOrdersWithProfile(filePath, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, orders, 200)
+	assert.Equal(t, expected, orders)
+}
+
+func BenchmarkReadOrders(b *testing.B) {
+	filePath := writeLargeWorkbook(b, 20000)
+	repo := &NetworkPathRepository{Profile: DefaultImportProfile()}
+
+	b.Run("GetRows", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := getOrdersWithGetRows(filePath, repo.Profile); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("StreamingRows", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			count := 0
+			err := repo.EachOrder(filePath, nil, func(models.PurchaseOrder) error {
+				count++
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
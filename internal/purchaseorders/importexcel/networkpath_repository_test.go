@@ -1,14 +1,20 @@
 package importexcel
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderstatus"
 	"purchase-record/internal/purchaseorders/utils"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
 )
 
 func TestNetworkPathRepository_GetOrdersFromNetworkPath(t *testing.T) {
@@ -32,12 +38,12 @@ func TestNetworkPathRepository_GetOrdersFromNetworkPath(t *testing.T) {
 		Received:           utils.IntOrNil("50"),
 		Remain:             utils.IntOrNil("50"),
 		PR:                 utils.StringOrNil("PR123"),
-		PRDate:             utils.StringOrNil("2024-01-01"),
+		PRDate:             utils.DateOrNil("2024-01-01"),
 		PO:                 utils.StringOrNil("PO123"),
-		PODate:             utils.StringOrNil("2024-01-15"),
+		PODate:             utils.DateOrNil("2024-01-15"),
 		Distribution:       utils.StringOrNil("Dept1"),
-		RequestDate:        utils.StringOrNil("2024-01-01"),
-		DeliveryDate:       utils.StringOrNil("2024-06-30"),
+		RequestDate:        utils.DateOrNil("2024-01-01"),
+		DeliveryDate:       utils.DateOrNil("2024-06-30"),
 		Status:             utils.StringOrNil("Active"),
 	}
 
@@ -53,12 +59,12 @@ func TestNetworkPathRepository_GetOrdersFromNetworkPath(t *testing.T) {
 		Received:           utils.IntOrNil("100"),
 		Remain:             utils.IntOrNil("100"),
 		PR:                 utils.StringOrNil("PR456"),
-		PRDate:             utils.StringOrNil("2024-02-01"),
+		PRDate:             utils.DateOrNil("2024-02-01"),
 		PO:                 utils.StringOrNil("PO456"),
-		PODate:             utils.StringOrNil("2024-02-15"),
+		PODate:             utils.DateOrNil("2024-02-15"),
 		Distribution:       utils.StringOrNil("Dept2"),
-		RequestDate:        utils.StringOrNil("2024-02-01"),
-		DeliveryDate:       utils.StringOrNil("2024-07-30"),
+		RequestDate:        utils.DateOrNil("2024-02-01"),
+		DeliveryDate:       utils.DateOrNil("2024-07-30"),
 		Status:             utils.StringOrNil("Active"),
 	}
 
@@ -171,3 +177,478 @@ func (m *MockNetworkPathRepository) GetOrdersFromNetworkPath(filePath string) ([
 	}
 	return nil, fmt.Errorf("no test case for file: %s", filePath)
 }
+
+func (m *MockNetworkPathRepository) GetOrdersWithProfile(filePath string, profile *models.ImportProfile) ([]models.PurchaseOrder, error) {
+	return m.GetOrdersFromNetworkPath(filePath)
+}
+
+func (m *MockNetworkPathRepository) LoadProfile(profilePath string) (*models.ImportProfile, error) {
+	return DefaultImportProfile(), nil
+}
+
+func (m *MockNetworkPathRepository) EachOrder(filePath string, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	orders, err := m.GetOrdersFromNetworkPath(filePath)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockNetworkPathRepository) EachOrderFromReader(reader io.Reader, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	return fmt.Errorf("reading from a stream is not supported by this mock")
+}
+
+func (m *MockNetworkPathRepository) CheckWorkbook(reader io.Reader, profile *models.ImportProfile) error {
+	return fmt.Errorf("reading from a stream is not supported by this mock")
+}
+
+func (m *MockNetworkPathRepository) GetSheets(filePath string) ([]models.SheetInfo, error) {
+	return nil, fmt.Errorf("no test case for file: %s", filePath)
+}
+
+// writeTestWorkbook creates a workbook whose second sheet holds the given
+// header and data rows, mirroring the layout of the purchasing workbook.
+func writeTestWorkbook(t *testing.T, headerRows [][]string, dataRows [][]string) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if _, err := f.NewSheet("PO"); err != nil {
+		t.Fatalf("Failed to create sheet: %v", err)
+	}
+	for i, row := range append(headerRows, dataRows...) {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("PO", cell, &row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	if err := f.SaveAs(filePath); err != nil {
+		t.Fatalf("Failed to save workbook: %v", err)
+	}
+	return filePath
+}
+
+func TestNetworkPathRepository_HeaderMapping(t *testing.T) {
+	headerRows := [][]string{
+		{"Purchase Order Tracking"},
+		{},
+		{"Job ID No.", "Remark", "Customer Name", "Inserted Column", "Product Code", "Ordered", "Received", "Remain", "PO No", "Stock Picking Out Date", "PR Date"},
+	}
+
+	t.Run("fields are located by header text", func(t *testing.T) {
+		filePath := writeTestWorkbook(t, headerRows, [][]string{
+			{"J-001", "urgent", "Customer A", "ignored", "PROD123", "8", "5", "3", "PO123", "12/03/24 (5)\n20/03/24 (3)", "45292"},
+			{"", "blank job id is skipped"},
+			{"J-002", "", "Customer B", "", "PROD456", "10", "", "10", "", "", "31/02/2567"},
+		})
+
+		repo := NewNetworkPathRepository()
+		orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		assert.NoError(t, err)
+		assert.Len(t, orders, 2)
+		assert.Equal(t, "J-001", *orders[0].JobIDNo)
+		assert.Equal(t, "urgent", *orders[0].Remark)
+		assert.Equal(t, "Customer A", *orders[0].Customer)
+		assert.Equal(t, "PROD123", *orders[0].ProductCode)
+		assert.Equal(t, 8, *orders[0].Ordered)
+		assert.Equal(t, 3, *orders[0].Remain)
+		assert.Equal(t, "PO123", *orders[0].PO)
+		assert.Equal(t, "Completed", *orders[0].Status)
+		assert.Equal(t, "delivered all 8 units", *orders[0].StatusReason)
+		assert.Equal(t, "2024-03-20", orders[0].StockPickingOutDate.String())
+		assert.Equal(t, "12/03/24 (5)\n20/03/24 (3)", *orders[0].DeliveryLog)
+		assert.Equal(t, "2024-03-20", orders[0].DeliveryDate.String())
+		assert.Len(t, orders[0].Shipments, 2)
+		assert.Equal(t, "12/03/24 (5)", orders[0].Shipments[0].Raw)
+		assert.Equal(t, 3, *orders[0].Shipments[1].Quantity)
+		assert.Equal(t, "2024-01-01", orders[0].PRDate.String())
+		assert.Empty(t, orders[0].Warnings)
+		assert.Nil(t, orders[0].Type)
+		assert.Nil(t, orders[1].Remark)
+		assert.Nil(t, orders[1].Received)
+		assert.Nil(t, orders[1].StockPickingOutDate)
+		assert.Nil(t, orders[1].DeliveryDate)
+		assert.Empty(t, orders[1].Shipments)
+		assert.Equal(t, "Not Started", *orders[1].Status)
+		assert.Equal(t, "delivered 0 of 10 units", *orders[1].StatusReason)
+
+		// Unparseable dates are kept as warnings rather than dropped silently
+		assert.Nil(t, orders[1].PRDate)
+		assert.Equal(t, []models.ImportWarning{{
+			Row:     6,
+			Field:   FieldPRDate,
+			Value:   "31/02/2567",
+			Message: "'31/02/2567' is not a recognized date",
+		}}, orders[1].Warnings)
+	})
+
+	t.Run("formula results are read as whole numbers", func(t *testing.T) {
+		filePath := writeTestWorkbook(t, headerRows, [][]string{
+			{"J-001", "", "Customer A", "", "PROD123", "3", "", "", "", "", ""},
+			{"J-002", "", "Customer B", "", "PROD456", "10", "", "2.5", "", "", ""},
+		})
+
+		// Excel keeps the result of =0.7*3+0.9 as 2.9999999999999996 next to the
+		// formula, and that raw value is what is read
+		seven, nine := 0.7, 0.9
+		require.NotEqual(t, 3.0, seven*3+nine)
+		f, err := excelize.OpenFile(filePath)
+		require.NoError(t, err)
+		require.NoError(t, f.SetCellFloat("PO", "H4", seven*3+nine, -1, 64))
+		require.NoError(t, f.SetCellFormula("PO", "H4", "0.7*3+0.9"))
+		require.NoError(t, f.SetCellFloat("PO", "G4", 1.0000000001e-3, -1, 64))
+		require.NoError(t, f.Save())
+		require.NoError(t, f.Close())
+
+		repo := NewNetworkPathRepository()
+		orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		require.NotNil(t, orders[0].Remain)
+		assert.Equal(t, 3, *orders[0].Remain)
+		// Values that are not close to a whole number are still not read as one
+		assert.Nil(t, orders[0].Received)
+		assert.Nil(t, orders[1].Remain)
+	})
+
+	t.Run("missing required headers are reported", func(t *testing.T) {
+		filePath := writeTestWorkbook(t, [][]string{{"Job ID No", "Product Code", "Stock Picking Out Date"}}, nil)
+
+		repo := NewNetworkPathRepository()
+		_, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `missing required headers: "Customer", "Ordered"`)
+	})
+}
+
+func TestNetworkPathRepository_StatusRules(t *testing.T) {
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}},
+		[][]string{
+			{"J-001", "Customer A", "PROD1", "8", "12/03/24 (5)"},
+			{"J-002", "Customer B", "PROD2", "2", "12/03/24 (3)"},
+		},
+	)
+
+	t.Run("custom status engine", func(t *testing.T) {
+		profile := DefaultImportProfile()
+		profile.HeaderRows = 1
+		repo := &NetworkPathRepository{
+			Profile: profile,
+			StatusEngine: orderstatus.NewStatusEngine(orderstatus.StatusRuleFunc(func(f orderstatus.Facts) (orderstatus.Result, bool) {
+				return orderstatus.Result{Status: "Shipped", Reason: fmt.Sprintf("%d units out", f.Delivered)}, true
+			})),
+		}
+
+		orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		assert.NoError(t, err)
+		assert.Len(t, orders, 2)
+		assert.Equal(t, "Shipped", *orders[0].Status)
+		assert.Equal(t, "5 units out", *orders[0].StatusReason)
+	})
+
+	t.Run("delivered_units keeps two labels", func(t *testing.T) {
+		profile := DefaultImportProfile()
+		profile.HeaderRows = 1
+		profile.StatusRule = models.StatusRule{Type: StatusRuleDeliveredUnits}
+		assert.NoError(t, normalizeImportProfile(profile))
+		repo := &NetworkPathRepository{Profile: profile}
+
+		orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Not Completed", *orders[0].Status)
+		assert.Equal(t, "Not Completed", *orders[1].Status)
+		assert.Nil(t, orders[0].StatusReason)
+	})
+
+	t.Run("default rules", func(t *testing.T) {
+		profile := DefaultImportProfile()
+		profile.HeaderRows = 1
+		repo := &NetworkPathRepository{Profile: profile}
+
+		orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+		assert.NoError(t, err)
+		assert.Equal(t, orderstatus.PartiallyDelivered, *orders[0].Status)
+		assert.Equal(t, orderstatus.OverDelivered, *orders[1].Status)
+	})
+}
+
+func TestNetworkPathRepository_DeliveryLogWarnings(t *testing.T) {
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}},
+		[][]string{{"J-001", "Customer A", "PROD1", "8", "12/03/24 (5 pcs), await stock"}},
+	)
+	profile := DefaultImportProfile()
+	profile.HeaderRows = 1
+	repo := &NetworkPathRepository{Profile: profile}
+
+	orders, err := repo.GetOrdersFromNetworkPath(filePath)
+
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, "pcs", orders[0].Shipments[0].Unit)
+	assert.Equal(t, "2024-03-12", orders[0].DeliveryDate.String())
+	assert.Equal(t, []models.ImportWarning{{
+		Row:     2,
+		Field:   FieldDeliveryLog,
+		Value:   "await stock",
+		Message: "'await stock' is not a recognized delivery entry",
+	}}, orders[0].Warnings)
+}
+
+func TestNetworkPathRepository_EachOrderFromReader(t *testing.T) {
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}},
+		[][]string{
+			{"J-001", "Customer A", "PROD1", "8", "12/03/24 (8)"},
+			{"J-002", "Customer B", "PROD2", "2", ""},
+		},
+	)
+	profile := DefaultImportProfile()
+	profile.HeaderRows = 1
+	repo := &NetworkPathRepository{Profile: profile}
+
+	file, err := os.Open(filePath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var jobIDs []string
+	err = repo.EachOrderFromReader(file, nil, func(order models.PurchaseOrder) error {
+		jobIDs = append(jobIDs, *order.JobIDNo)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"J-001", "J-002"}, jobIDs)
+
+	err = repo.EachOrderFromReader(strings.NewReader("not a workbook"), nil, func(models.PurchaseOrder) error { return nil })
+	assert.ErrorContains(t, err, "failed to open Excel workbook")
+}
+
+func TestNetworkPathRepository_CheckWorkbook(t *testing.T) {
+	profile := DefaultImportProfile()
+	profile.HeaderRows = 1
+	repo := &NetworkPathRepository{Profile: profile}
+
+	read := func(filePath string) []byte {
+		data, err := os.ReadFile(filePath)
+		assert.NoError(t, err)
+		return data
+	}
+	valid := read(writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}},
+		[][]string{{"J-001", "Customer A", "PROD1", "8", ""}},
+	))
+
+	tests := []struct {
+		name          string
+		data          []byte
+		expectedError string
+	}{
+		{name: "valid workbook", data: valid},
+		{name: "headers only", data: read(writeTestWorkbook(t, [][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}}, nil))},
+		{name: "truncated file", data: valid[:len(valid)/2], expectedError: "failed to open Excel workbook"},
+		{name: "missing header", data: read(writeTestWorkbook(t, [][]string{{"Job ID No", "Customer"}}, nil)), expectedError: "invalid header in sheet 'PO'"},
+		{name: "empty sheet", data: read(writeTestWorkbook(t, nil, nil)), expectedError: "sheet 'PO' is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.CheckWorkbook(bytes.NewReader(tt.data), nil)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// The sheet chosen by the profile must exist
+	missingSheet := cloneImportProfile(profile)
+	missingSheet.SheetName = "Orders"
+	err := repo.CheckWorkbook(bytes.NewReader(valid), missingSheet)
+	assert.ErrorContains(t, err, "sheet 'Orders' not found")
+}
+
+func TestNetworkPathRepository_GetOrdersWithProfile(t *testing.T) {
+	// Variant workbook: data on the second sheet, a single header row and
+	// quantities written with thousands separators
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job", "Client", "Qty", "Shipped", "State"}},
+		[][]string{
+			{"J-100", "Customer C", "1,200", "ignored", "Open"},
+			{"J-101", "Customer D", "8.00", "", "Closed"},
+		},
+	)
+
+	profilePath := filepath.Join(t.TempDir(), "variant.yaml")
+	err := os.WriteFile(profilePath, []byte(`
+name: variant
+sheet_index: 1
+header_rows: 1
+columns:
+  - field: job_id_no
+    headers: ["Job"]
+  - field: customer
+    column: B
+  - field: ordered
+    headers: ["Qty"]
+    type: number
+  - field: status
+    headers: ["State"]
+    type: trim
+status_rule:
+  type: column
+`), 0644)
+	assert.NoError(t, err)
+
+	repo := NewNetworkPathRepository()
+	profile, err := repo.LoadProfile(profilePath)
+	assert.NoError(t, err)
+
+	orders, err := repo.GetOrdersWithProfile(filePath, profile)
+
+	assert.NoError(t, err)
+	assert.Len(t, orders, 2)
+	assert.Equal(t, "Customer C", *orders[0].Customer)
+	assert.Equal(t, 1200, *orders[0].Ordered)
+	assert.Equal(t, "Open", *orders[0].Status)
+	assert.Equal(t, 8, *orders[1].Ordered)
+	assert.Equal(t, "Closed", *orders[1].Status)
+	assert.Nil(t, orders[0].PR)
+}
+
+func TestLoadImportProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		fileName      string
+		content       string
+		expectedError string
+	}{
+		{
+			name:     "json profile with defaults",
+			fileName: "profile.json",
+			content:  `{"sheet_index": 0, "columns": [{"field": "job_id_no", "headers": ["Job"]}, {"field": "ordered", "column": "C"}, {"field": "delivery_log", "column": "D"}]}`,
+		},
+		{
+			name:          "unknown field",
+			fileName:      "profile.yaml",
+			content:       "columns:\n  - field: colour\n    headers: [Colour]\n",
+			expectedError: `unknown field "colour"`,
+		},
+		{
+			name:          "unsupported coercion",
+			fileName:      "profile.yaml",
+			content:       "columns:\n  - field: job_id_no\n    headers: [Job]\n    type: number\n",
+			expectedError: `field "job_id_no" does not support type "number"`,
+		},
+		{
+			name:          "status rule without delivery column",
+			fileName:      "profile.yaml",
+			content:       "columns:\n  - field: job_id_no\n    headers: [Job]\n",
+			expectedError: `status_rule delivery_field "delivery_log" must be a mapped text field`,
+		},
+		{
+			name:          "unsupported format",
+			fileName:      "profile.toml",
+			content:       "",
+			expectedError: "unsupported profile format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profilePath := filepath.Join(t.TempDir(), tt.fileName)
+			assert.NoError(t, os.WriteFile(profilePath, []byte(tt.content), 0644))
+
+			profile, err := LoadImportProfile(profilePath)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, FieldJobIDNo, profile.KeyField)
+			assert.Equal(t, StatusRuleDeliveryStatus, profile.StatusRule.Type)
+			assert.Equal(t, FieldDeliveryLog, profile.StatusRule.DeliveryField)
+			assert.Equal(t, CoerceInt, profile.Columns[1].Type)
+		})
+	}
+}
+
+func TestNetworkPathRepository_SheetSelection(t *testing.T) {
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code", "Ordered", "Stock Picking Out Date"}},
+		[][]string{{"J-001", "Customer A", "PROD123", "1", ""}},
+	)
+
+	index := func(i int) *int { return &i }
+
+	tests := []struct {
+		name          string
+		selector      models.SheetSelector
+		expectedError string
+	}{
+		{name: "default index", selector: models.SheetSelector{}},
+		{name: "exact name", selector: models.SheetSelector{Sheet: "PO"}},
+		{name: "pattern", selector: models.SheetSelector{SheetPattern: "(?i)^p"}},
+		{name: "explicit index", selector: models.SheetSelector{SheetIndex: index(1)}},
+		{name: "unknown name", selector: models.SheetSelector{Sheet: "Orders"}, expectedError: "sheet 'Orders' not found in Excel file, available sheets: Sheet1, PO"},
+		{name: "pattern without match", selector: models.SheetSelector{SheetPattern: "^2024"}, expectedError: "no sheet matching '^2024'"},
+		{name: "invalid pattern", selector: models.SheetSelector{SheetPattern: "("}, expectedError: "invalid sheet pattern"},
+		{name: "index out of range", selector: models.SheetSelector{SheetIndex: index(5)}, expectedError: "sheet at index 5 not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewNetworkPathRepository()
+			profile, err := repo.LoadProfile("")
+			assert.NoError(t, err)
+			profile.HeaderRows = 1
+			applySheetSelector(profile, tt.selector)
+
+			orders, err := repo.GetOrdersWithProfile(filePath, profile)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, orders, 1)
+		})
+	}
+}
+
+func TestNetworkPathRepository_GetSheets(t *testing.T) {
+	filePath := writeTestWorkbook(t,
+		[][]string{{"Job ID No", "Customer", "Product Code"}},
+		[][]string{{"J-001", "Customer A"}, {"J-002"}},
+	)
+
+	repo := NewNetworkPathRepository()
+	sheets, err := repo.GetSheets(filePath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.SheetInfo{
+		{Index: 0, Name: "Sheet1", Rows: 0, Columns: 0, Visible: true},
+		{Index: 1, Name: "PO", Rows: 3, Columns: 3, Visible: true},
+	}, sheets)
+}
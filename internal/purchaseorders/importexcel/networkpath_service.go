@@ -1,15 +1,63 @@
 package importexcel
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/ordercache"
+	"purchase-record/internal/purchaseorders/orderdiff"
+	"purchase-record/internal/purchaseorders/orderquery"
+	"purchase-record/internal/purchaseorders/orderstore"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrStoreDisabled is returned by the snapshot methods when the service has
+// no order store.
+var ErrStoreDisabled = errors.New("snapshots need the order store, which is not enabled")
+
+// ErrSnapshotNotFound is returned for a snapshot ID the store does not have
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// ErrCacheDisabled is returned by the cache methods when the service has no
+// cache of parsed workbooks.
+var ErrCacheDisabled = errors.New("the cache of parsed workbooks is not enabled")
+
+// ErrWorkbookNotLoaded is returned for a workbook whose content was not read
+// when there is no stored import to serve in its place
+var ErrWorkbookNotLoaded = errors.New("workbook was not read and has no stored import")
+
 type INetworkPathService interface {
 	GetOrdersFromPath(filePath string) ([]models.PurchaseOrder, error)
+	GetOrdersFromPathWithOptions(filePath string, opts models.ImportOptions) ([]models.PurchaseOrder, error)
+	QueryOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery) (models.OrderPage, error)
+	StreamOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, fn func(models.PurchaseOrder) error) error
+	SummarizeOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, groupBy string) (models.OrderSummary, error)
+	ImportOrdersFromReader(reader io.Reader, opts models.ImportOptions) ([]models.PurchaseOrder, error)
+	ImportWorkbook(workbook models.Workbook, opts models.ImportOptions) (int64, error)
+	ValidateWorkbook(reader io.Reader, opts models.ImportOptions) error
+	GetSheetsFromPath(filePath string) ([]models.SheetInfo, error)
+	CachedOrders(workbook models.Workbook, opts models.ImportOptions) ([]models.PurchaseOrder, bool)
+	CacheStats() (models.CacheStats, error)
+	InvalidateCache(path string) (int, error)
+	LatestSnapshot(sourcePath string) (models.StoredImport, bool)
+	ListSnapshots(sourcePath string) ([]models.StoredImport, error)
+	DiffSnapshots(fromID, toID int64) (models.SnapshotDiff, error)
 }
 
 type NetworkPathService struct {
 	Repository INetworkPathRepository
+	// Store keeps the orders of every import when set
+	Store orderstore.IOrderStoreRepository
+	// Cache keeps the orders of recently parsed workbooks when set
+	Cache ordercache.IOrderCache
+
+	// parses lets concurrent imports of the same content share one parse
+	parses singleflight.Group
 }
 
 func NewNetworkPathService() INetworkPathService {
@@ -18,6 +66,17 @@ func NewNetworkPathService() INetworkPathService {
 	}
 }
 
+// NewNetworkPathServiceWithStorage saves the orders of each import into store
+// and serves them from it when the workbook cannot be reached, and keeps
+// recently parsed workbooks in cache. Either may be nil.
+func NewNetworkPathServiceWithStorage(store orderstore.IOrderStoreRepository, cache ordercache.IOrderCache) INetworkPathService {
+	return &NetworkPathService{
+		Repository: NewNetworkPathRepository(),
+		Store:      store,
+		Cache:      cache,
+	}
+}
+
 func (s *NetworkPathService) GetOrdersFromPath(filePath string) ([]models.PurchaseOrder, error) {
 	// Windows UNC paths with double backslashes are already correctly formatted
 	// for the excelize library to process, so no conversion is needed
@@ -25,3 +84,381 @@ func (s *NetworkPathService) GetOrdersFromPath(filePath string) ([]models.Purcha
 	// Get all orders from the repository
 	return s.Repository.GetOrdersFromNetworkPath(filePath)
 }
+
+func (s *NetworkPathService) GetOrdersFromPathWithOptions(filePath string, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.GetOrdersWithProfile(filePath, profile)
+}
+
+// QueryOrders returns the page of orders matching the query's search and
+// filters. Unless the query is sorted, only the requested page is collected
+// while the workbook is parsed, though a service with a store or a cache
+// holds every order of the workbook to keep them.
+func (s *NetworkPathService) QueryOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery) (models.OrderPage, error) {
+	paginator, err := orderquery.NewPaginator(query)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	var orders []models.PurchaseOrder
+	err = s.eachOrder(workbook, opts, profile, func(order models.PurchaseOrder) error {
+		if paginator.Add(order) {
+			orders = append(orders, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	return paginator.Page(orders), nil
+}
+
+// StreamOrders calls fn for every order on the query's page as it is parsed
+// from the workbook, or as it is read from the cache or store. Sorted queries
+// are delivered once the whole workbook has been parsed.
+func (s *NetworkPathService) StreamOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, fn func(models.PurchaseOrder) error) error {
+	paginator, err := orderquery.NewPaginator(query)
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return err
+	}
+
+	err = s.eachOrder(workbook, opts, profile, func(order models.PurchaseOrder) error {
+		if !paginator.Add(order) {
+			return nil
+		}
+		return fn(order)
+	})
+	if err != nil || !paginator.Sorted() {
+		return err
+	}
+
+	for _, order := range paginator.Page(nil).Orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SummarizeOrders totals the orders matching the query's search and filters
+// per groupBy value. Paging and sorting do not apply.
+func (s *NetworkPathService) SummarizeOrders(workbook models.Workbook, opts models.ImportOptions, query models.RequestQuery, groupBy string) (models.OrderSummary, error) {
+	summarizer, err := orderquery.NewSummarizer(groupBy)
+	if err != nil {
+		return models.OrderSummary{}, err
+	}
+	filter, err := orderquery.NewFilter(query.OrderFilter)
+	if err != nil {
+		return models.OrderSummary{}, err
+	}
+	terms := orderquery.SearchTerms(query.Search)
+
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return models.OrderSummary{}, err
+	}
+
+	err = s.eachOrder(workbook, opts, profile, func(order models.PurchaseOrder) error {
+		if orderquery.MatchesSearch(order, terms) && filter.Match(order) {
+			summarizer.Add(order)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.OrderSummary{}, err
+	}
+
+	return summarizer.Summary(), nil
+}
+
+// ImportOrdersFromReader reads every order of an uploaded workbook
+func (s *NetworkPathService) ImportOrdersFromReader(reader io.Reader, opts models.ImportOptions) ([]models.PurchaseOrder, error) {
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []models.PurchaseOrder{}
+	err = s.Repository.EachOrderFromReader(reader, profile, func(order models.PurchaseOrder) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ImportWorkbook parses every order of the workbook into the store and the
+// cache, as a request for them would, and returns how many there are
+func (s *NetworkPathService) ImportWorkbook(workbook models.Workbook, opts models.ImportOptions) (int64, error) {
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = s.eachOrder(workbook, opts, profile, func(models.PurchaseOrder) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ValidateWorkbook checks that a workbook, such as a copy about to become a
+// backup, opens and has the sheet and headers the options expect
+func (s *NetworkPathService) ValidateWorkbook(reader io.Reader, opts models.ImportOptions) error {
+	profile, err := s.resolveProfile(opts)
+	if err != nil {
+		return err
+	}
+	return s.Repository.CheckWorkbook(reader, profile)
+}
+
+// eachOrder calls fn for every order of the workbook, taken from its Orders
+// or the cache when it holds the workbook, or else parsed from the bytes already in memory
+// and passed on as it is parsed. With a store or a cache every parsed order is
+// also collected, to be saved as a snapshot of the source path unless the
+// workbook is unchanged and to be cached. The source's latest snapshot is used
+// instead when the workbook was not read.
+func (s *NetworkPathService) eachOrder(workbook models.Workbook, opts models.ImportOptions, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	if workbook.Orders != nil {
+		return each(workbook.Orders, fn)
+	}
+	sourcePath := opts.SourcePath(workbook.Path)
+	key := cacheKey(workbook, opts)
+	if s.Cache != nil {
+		if orders, found := s.Cache.Get(key, workbook); found {
+			return each(orders, fn)
+		}
+	}
+
+	if !workbook.Loaded() {
+		if s.Store == nil {
+			return fmt.Errorf("%w: '%s'", ErrWorkbookNotLoaded, sourcePath)
+		}
+		stored, found := s.storedOrders(sourcePath)
+		if !found {
+			return fmt.Errorf("%w: '%s'", ErrWorkbookNotLoaded, sourcePath)
+		}
+		return each(stored, fn)
+	}
+
+	// Nothing keeps the orders, so they are passed on as they are parsed
+	if s.Store == nil && s.Cache == nil {
+		return s.Repository.EachOrderFromReader(workbook.Reader(), profile, fn)
+	}
+
+	return s.parseOrders(key, workbook, profile, fn)
+}
+
+// parseOrders parses the workbook, passing each order to fn as it is parsed,
+// and keeps all of them in the store and the cache. Concurrent calls for the
+// same content and options share one parse: the first caller gets the orders
+// as they are parsed, the others once the parse is done. When fn fails the
+// parse still completes so the orders are kept, and its error is returned.
+func (s *NetworkPathService) parseOrders(key ordercache.Key, workbook models.Workbook, profile *models.ImportProfile, fn func(models.PurchaseOrder) error) error {
+	flight := strings.Join([]string{key.Path, key.Source, key.Options, workbook.Hash}, "\x00")
+	parsed := false
+	var fnErr error
+	result, err, _ := s.parses.Do(flight, func() (any, error) {
+		parsed = true
+		orders := []models.PurchaseOrder{}
+		err := s.Repository.EachOrderFromReader(workbook.Reader(), profile, func(order models.PurchaseOrder) error {
+			orders = append(orders, order)
+			if fnErr == nil {
+				fnErr = fn(order)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if s.Store != nil {
+			// Without a hash the snapshot is saved even if nothing changed
+			if _, err := s.Store.SaveImport(key.Source, workbook.Hash, orders); err != nil {
+				return nil, fmt.Errorf("failed to store orders of '%s': %w", key.Source, err)
+			}
+		}
+		if s.Cache != nil {
+			s.Cache.Put(key, workbook, orders)
+		}
+		return orders, nil
+	})
+	if err != nil {
+		return err
+	}
+	if parsed {
+		return fnErr
+	}
+	// The orders are shared with the other callers and must not be modified
+	return each(result.([]models.PurchaseOrder), fn)
+}
+
+// each calls fn for every order until it returns an error
+func each(orders []models.PurchaseOrder, fn func(models.PurchaseOrder) error) error {
+	for _, order := range orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheKey identifies the orders of workbook parsed with opts. The profile
+// is identified by its version as well as its path, so editing it makes the
+// orders parsed with the old mapping unreachable.
+func cacheKey(workbook models.Workbook, opts models.ImportOptions) ordercache.Key {
+	sheetIndex := ""
+	if opts.SheetIndex != nil {
+		sheetIndex = strconv.Itoa(*opts.SheetIndex)
+	}
+	return ordercache.Key{
+		Path:    workbook.Path,
+		Source:  opts.SourcePath(workbook.Path),
+		Options: strings.Join([]string{opts.Profile, profileVersion(opts.Profile), opts.Sheet, opts.SheetPattern, sheetIndex}, "\x00"),
+	}
+}
+
+// profileVersion describes the size and modification time of the profile
+// file, empty for the default profile or one that cannot be found
+func profileVersion(profilePath string) string {
+	if profilePath == "" {
+		return ""
+	}
+	info, err := os.Stat(profilePath)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// CachedOrders returns the cached orders of a workbook with the size and
+// modification time of workbook, so it need not be read. Passed back as the
+// workbook's Orders they are served even if the entry is dropped meanwhile.
+func (s *NetworkPathService) CachedOrders(workbook models.Workbook, opts models.ImportOptions) ([]models.PurchaseOrder, bool) {
+	if s.Cache == nil {
+		return nil, false
+	}
+	return s.Cache.Fresh(cacheKey(workbook, opts), workbook)
+}
+
+// CacheStats reports the hits and misses of the cache of parsed workbooks
+func (s *NetworkPathService) CacheStats() (models.CacheStats, error) {
+	if s.Cache == nil {
+		return models.CacheStats{}, ErrCacheDisabled
+	}
+	return s.Cache.Stats(), nil
+}
+
+// InvalidateCache drops the parsed workbooks read from or standing for path,
+// or all of them when it is empty, and returns how many were dropped
+func (s *NetworkPathService) InvalidateCache(path string) (int, error) {
+	if s.Cache == nil {
+		return 0, ErrCacheDisabled
+	}
+	return s.Cache.Invalidate(path), nil
+}
+
+// LatestSnapshot returns the latest stored import of sourcePath and whether
+// there is one
+func (s *NetworkPathService) LatestSnapshot(sourcePath string) (models.StoredImport, bool) {
+	if s.Store == nil {
+		return models.StoredImport{}, false
+	}
+	snapshot, found, err := s.Store.LatestImport(sourcePath)
+	return snapshot, err == nil && found
+}
+
+// ListSnapshots returns the stored imports of sourcePath, or of every source
+// when it is empty, newest first.
+func (s *NetworkPathService) ListSnapshots(sourcePath string) ([]models.StoredImport, error) {
+	if s.Store == nil {
+		return nil, ErrStoreDisabled
+	}
+	return s.Store.ListImports(sourcePath)
+}
+
+// DiffSnapshots reports the orders added, removed and changed from one stored
+// import to another.
+func (s *NetworkPathService) DiffSnapshots(fromID, toID int64) (models.SnapshotDiff, error) {
+	if s.Store == nil {
+		return models.SnapshotDiff{}, ErrStoreDisabled
+	}
+
+	from, fromOrders, err := s.snapshotOrders(fromID)
+	if err != nil {
+		return models.SnapshotDiff{}, err
+	}
+	to, toOrders, err := s.snapshotOrders(toID)
+	if err != nil {
+		return models.SnapshotDiff{}, err
+	}
+
+	return models.SnapshotDiff{From: from, To: to, OrderDiff: orderdiff.Diff(fromOrders, toOrders)}, nil
+}
+
+// snapshotOrders returns a stored import with its orders
+func (s *NetworkPathService) snapshotOrders(id int64) (models.StoredImport, []models.PurchaseOrder, error) {
+	snapshot, found, err := s.Store.GetImport(id)
+	if err != nil {
+		return models.StoredImport{}, nil, err
+	}
+	if !found {
+		return models.StoredImport{}, nil, fmt.Errorf("%w: %d", ErrSnapshotNotFound, id)
+	}
+	orders, err := s.Store.GetImportOrders(id)
+	if err != nil {
+		return models.StoredImport{}, nil, err
+	}
+	return snapshot, orders, nil
+}
+
+// storedOrders returns the orders of the latest stored import of sourcePath
+func (s *NetworkPathService) storedOrders(sourcePath string) ([]models.PurchaseOrder, bool) {
+	latest, found, err := s.Store.LatestImport(sourcePath)
+	if err != nil || !found {
+		return nil, false
+	}
+	orders, err := s.Store.GetImportOrders(latest.ID)
+	if err != nil {
+		return nil, false
+	}
+	return orders, true
+}
+
+// resolveProfile loads the requested profile and applies the caller's sheet choice
+func (s *NetworkPathService) resolveProfile(opts models.ImportOptions) (*models.ImportProfile, error) {
+	// Without a profile the repository returns its default layout
+	profile, err := s.Repository.LoadProfile(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// A sheet chosen by the caller overrides the profile's selection
+	applySheetSelector(profile, opts.SheetSelector)
+
+	return profile, nil
+}
+
+func (s *NetworkPathService) GetSheetsFromPath(filePath string) ([]models.SheetInfo, error) {
+	return s.Repository.GetSheets(filePath)
+}
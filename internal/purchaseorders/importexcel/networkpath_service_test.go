@@ -1,12 +1,26 @@
 package importexcel
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"purchase-record/config"
 	"purchase-record/internal/models"
 	"purchase-record/internal/purchaseorders/importexcel/mocks"
+	"purchase-record/internal/purchaseorders/ordercache"
+	"purchase-record/internal/purchaseorders/orderstore"
+	"purchase-record/internal/purchaseorders/utils"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
@@ -35,12 +49,12 @@ func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
 							Received:           intPtr(50),
 							Remain:             intPtr(50),
 							PR:                 stringPtr("PR123"),
-							PRDate:             stringPtr("2024-01-01"),
+							PRDate:             datePtr("2024-01-01"),
 							PO:                 stringPtr("PO123"),
-							PODate:             stringPtr("2024-01-15"),
+							PODate:             datePtr("2024-01-15"),
 							Distribution:       stringPtr("Dept1"),
-							RequestDate:        stringPtr("2024-01-01"),
-							DeliveryDate:       stringPtr("2024-06-30"),
+							RequestDate:        datePtr("2024-01-01"),
+							DeliveryDate:       datePtr("2024-06-30"),
 							Status:             stringPtr("Active"),
 						},
 						{
@@ -55,12 +69,12 @@ func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
 							Received:           intPtr(100),
 							Remain:             intPtr(100),
 							PR:                 stringPtr("PR456"),
-							PRDate:             stringPtr("2024-02-01"),
+							PRDate:             datePtr("2024-02-01"),
 							PO:                 stringPtr("PO456"),
-							PODate:             stringPtr("2024-02-15"),
+							PODate:             datePtr("2024-02-15"),
 							Distribution:       stringPtr("Dept2"),
-							RequestDate:        stringPtr("2024-02-01"),
-							DeliveryDate:       stringPtr("2024-07-30"),
+							RequestDate:        datePtr("2024-02-01"),
+							DeliveryDate:       datePtr("2024-07-30"),
 							Status:             stringPtr("Active"),
 						},
 					}, nil)
@@ -78,12 +92,12 @@ func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
 					Received:           intPtr(50),
 					Remain:             intPtr(50),
 					PR:                 stringPtr("PR123"),
-					PRDate:             stringPtr("2024-01-01"),
+					PRDate:             datePtr("2024-01-01"),
 					PO:                 stringPtr("PO123"),
-					PODate:             stringPtr("2024-01-15"),
+					PODate:             datePtr("2024-01-15"),
 					Distribution:       stringPtr("Dept1"),
-					RequestDate:        stringPtr("2024-01-01"),
-					DeliveryDate:       stringPtr("2024-06-30"),
+					RequestDate:        datePtr("2024-01-01"),
+					DeliveryDate:       datePtr("2024-06-30"),
 					Status:             stringPtr("Active"),
 				},
 				{
@@ -98,12 +112,12 @@ func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
 					Received:           intPtr(100),
 					Remain:             intPtr(100),
 					PR:                 stringPtr("PR456"),
-					PRDate:             stringPtr("2024-02-01"),
+					PRDate:             datePtr("2024-02-01"),
 					PO:                 stringPtr("PO456"),
-					PODate:             stringPtr("2024-02-15"),
+					PODate:             datePtr("2024-02-15"),
 					Distribution:       stringPtr("Dept2"),
-					RequestDate:        stringPtr("2024-02-01"),
-					DeliveryDate:       stringPtr("2024-07-30"),
+					RequestDate:        datePtr("2024-02-01"),
+					DeliveryDate:       datePtr("2024-07-30"),
 					Status:             stringPtr("Active"),
 				},
 			},
@@ -159,6 +173,466 @@ func TestNetworkPathService_GetOrdersFromPath(t *testing.T) {
 	}
 }
 
+func TestNetworkPathService_GetOrdersFromPathWithOptions(t *testing.T) {
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("123")}}
+
+	tests := []struct {
+		name           string
+		opts           models.ImportOptions
+		mockSetup      func(*mocks.INetworkPathRepository)
+		expectedOrders []models.PurchaseOrder
+		expectedError  error
+	}{
+		{
+			name: "default profile",
+			opts: models.ImportOptions{},
+			mockSetup: func(m *mocks.INetworkPathRepository) {
+				m.On("LoadProfile", "").Return(&models.ImportProfile{Name: "default", SheetIndex: 1}, nil)
+				m.On("GetOrdersWithProfile", "test.xlsx", &models.ImportProfile{Name: "default", SheetIndex: 1}).Return(orders, nil)
+			},
+			expectedOrders: orders,
+		},
+		{
+			name: "profile loaded from file",
+			opts: models.ImportOptions{Profile: "variant.yaml"},
+			mockSetup: func(m *mocks.INetworkPathRepository) {
+				m.On("LoadProfile", "variant.yaml").Return(&models.ImportProfile{Name: "variant"}, nil)
+				m.On("GetOrdersWithProfile", "test.xlsx", &models.ImportProfile{Name: "variant"}).Return(orders, nil)
+			},
+			expectedOrders: orders,
+		},
+		{
+			name: "sheet selection overrides the profile",
+			opts: models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO 2024"}},
+			mockSetup: func(m *mocks.INetworkPathRepository) {
+				m.On("LoadProfile", "").Return(&models.ImportProfile{Name: "default", SheetPattern: "^PO", SheetIndex: 1}, nil)
+				m.On("GetOrdersWithProfile", "test.xlsx", &models.ImportProfile{Name: "default", SheetName: "PO 2024", SheetIndex: 1}).Return(orders, nil)
+			},
+			expectedOrders: orders,
+		},
+		{
+			name: "profile error",
+			opts: models.ImportOptions{Profile: "missing.yaml"},
+			mockSetup: func(m *mocks.INetworkPathRepository) {
+				m.On("LoadProfile", "missing.yaml").Return(nil, errors.New("profile error"))
+			},
+			expectedError: errors.New("profile error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.INetworkPathRepository)
+			tt.mockSetup(mockRepo)
+
+			service := &NetworkPathService{Repository: mockRepo}
+
+			result, err := service.GetOrdersFromPathWithOptions("test.xlsx", tt.opts)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedOrders, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNetworkPathService_QueryOrders(t *testing.T) {
+	orders := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme")},
+		{JobIDNo: stringPtr("J-002"), Customer: stringPtr("Globex")},
+		{JobIDNo: stringPtr("J-003"), Customer: stringPtr("Acme")},
+		{JobIDNo: stringPtr("J-004"), Customer: stringPtr("Acme")},
+	}
+	emit := func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}
+
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(emit).Return(nil)
+
+	service := &NetworkPathService{Repository: mockRepo}
+	workbook := newWorkbook("test.xlsx", "workbook")
+
+	page, err := service.QueryOrders(workbook, models.ImportOptions{}, models.RequestQuery{Search: "acme", PageNo: 2, PageSize: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderPage{
+		Orders:     []models.PurchaseOrder{orders[3]},
+		Total:      3,
+		PageNo:     2,
+		PageSize:   2,
+		TotalPages: 2,
+	}, page)
+
+	var streamed []models.PurchaseOrder
+	err = service.StreamOrders(workbook, models.ImportOptions{}, models.RequestQuery{Search: "acme", PageSize: 2}, func(order models.PurchaseOrder) error {
+		streamed = append(streamed, order)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PurchaseOrder{orders[0], orders[2]}, streamed)
+	mockRepo.AssertExpectations(t)
+
+	// The workbook's bytes are parsed rather than the file at its path
+	read, err := io.ReadAll(mockRepo.Calls[1].Arguments.Get(0).(io.Reader))
+	require.NoError(t, err)
+	assert.Equal(t, "workbook", string(read))
+
+	// Without a store there is nothing to serve for a workbook that was not read
+	_, err = service.QueryOrders(models.Workbook{Path: "test.xlsx"}, models.ImportOptions{}, models.RequestQuery{})
+	assert.ErrorIs(t, err, ErrWorkbookNotLoaded)
+}
+
+func TestNetworkPathService_SummarizeOrders(t *testing.T) {
+	orders := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme"), SalesTeam: stringPtr("Team A"), Ordered: intPtr(10), Received: intPtr(10), Remain: intPtr(0)},
+		{JobIDNo: stringPtr("J-002"), Customer: stringPtr("Globex"), SalesTeam: stringPtr("Team B"), Ordered: intPtr(5), Received: intPtr(1), Remain: intPtr(4)},
+		{JobIDNo: stringPtr("J-003"), Customer: stringPtr("Acme"), SalesTeam: stringPtr("Team B"), Ordered: intPtr(30), Received: intPtr(0), Remain: intPtr(30)},
+	}
+	emit := func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}
+
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(emit).Return(nil)
+
+	service := &NetworkPathService{Repository: mockRepo}
+	workbook := newWorkbook("test.xlsx", "workbook")
+
+	query := models.RequestQuery{Search: "acme", PageSize: 1, OrderFilter: models.OrderFilter{Sort: "-ordered"}}
+	summary, err := service.SummarizeOrders(workbook, models.ImportOptions{}, query, "sales_team")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderSummary{
+		GroupBy: "sales_team",
+		Groups: []models.SummaryGroup{
+			{Key: "Team A", Lines: 1, CompletedLines: 1, Ordered: 10, Received: 10, Remain: 0, CompletionPercent: 100},
+			{Key: "Team B", Lines: 1, CompletedLines: 0, Ordered: 30, Received: 0, Remain: 30, CompletionPercent: 0},
+		},
+		Total: models.SummaryGroup{Lines: 2, CompletedLines: 1, Ordered: 40, Received: 10, Remain: 30, CompletionPercent: 25},
+	}, summary)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.SummarizeOrders(workbook, models.ImportOptions{}, models.RequestQuery{}, "product_code")
+	assert.EqualError(t, err, "groupBy must be one of customer, sales_team, project_manager, purchasing, status")
+}
+
+func TestNetworkPathService_Store(t *testing.T) {
+	store, err := orderstore.NewOrderStoreRepository(filepath.Join(t.TempDir(), "orders.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	backup := newWorkbook("backup/orders.xlsx", "workbook")
+	broken := newWorkbook("backup/orders.xlsx", "half saved")
+	source := `\\share\orders.xlsx`
+
+	orders := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme")},
+		{JobIDNo: stringPtr("J-002"), Customer: stringPtr("Globex")},
+	}
+	emit := func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(emit).Return(nil).Once()
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Return(errors.New("invalid header"))
+
+	service := &NetworkPathService{Repository: mockRepo, Store: store}
+	_, found := service.LatestSnapshot(source)
+	assert.False(t, found)
+
+	// Orders read from a copy are stored under the original source
+	page, err := service.QueryOrders(backup, models.ImportOptions{Source: source}, models.RequestQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, orders, page.Orders)
+	snapshot, found := service.LatestSnapshot(source)
+	assert.True(t, found)
+	assert.Equal(t, len(orders), snapshot.RowCount)
+	assert.Equal(t, backup.Hash, snapshot.ContentHash)
+
+	// A workbook that was not read is served from its latest import
+	summary, err := service.SummarizeOrders(models.Workbook{Path: source}, models.ImportOptions{}, models.RequestQuery{Search: "acme"}, "customer")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Total.Lines)
+	count, err := service.ImportWorkbook(models.Workbook{Path: source}, models.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(orders)), count)
+
+	// Without a stored import there is nothing to serve
+	_, err = service.QueryOrders(models.Workbook{Path: "missing.xlsx"}, models.ImportOptions{}, models.RequestQuery{})
+	assert.ErrorIs(t, err, ErrWorkbookNotLoaded)
+
+	// A workbook that was read but cannot be imported is not replaced by stored orders
+	_, err = service.QueryOrders(broken, models.ImportOptions{Source: source}, models.RequestQuery{})
+	assert.EqualError(t, err, "invalid header")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNetworkPathService_Cache(t *testing.T) {
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001"), Customer: stringPtr("Acme")}}
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}).Return(nil).Twice()
+
+	cache := ordercache.NewOrderCache(config.CacheConfig{MaxEntries: 4})
+	service := &NetworkPathService{Repository: mockRepo, Cache: cache}
+	workbook := newWorkbook("PO.xlsx", "v1")
+	workbook.ModTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	unread := models.Workbook{Path: workbook.Path, Size: workbook.Size, ModTime: workbook.ModTime}
+
+	_, found := service.CachedOrders(unread, models.ImportOptions{})
+	assert.False(t, found)
+	page, err := service.QueryOrders(workbook, models.ImportOptions{}, models.RequestQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, orders, page.Orders)
+
+	// The unchanged workbook is served without being read or parsed again,
+	// even once its entry is gone
+	unread.Orders, found = service.CachedOrders(unread, models.ImportOptions{})
+	assert.True(t, found)
+	removed, err := service.InvalidateCache("PO.xlsx")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	summary, err := service.SummarizeOrders(unread, models.ImportOptions{}, models.RequestQuery{}, "customer")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Total.Lines)
+
+	// Another sheet is parsed separately
+	_, found = service.CachedOrders(unread, models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO"}})
+	assert.False(t, found)
+	_, err = service.QueryOrders(workbook, models.ImportOptions{}, models.RequestQuery{})
+	require.NoError(t, err)
+
+	stats, err := service.CacheStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	mockRepo.AssertExpectations(t)
+
+	withoutCache := &NetworkPathService{Repository: mockRepo}
+	_, found = withoutCache.CachedOrders(unread, models.ImportOptions{})
+	assert.False(t, found)
+	_, err = withoutCache.CacheStats()
+	assert.ErrorIs(t, err, ErrCacheDisabled)
+	_, err = withoutCache.InvalidateCache("")
+	assert.ErrorIs(t, err, ErrCacheDisabled)
+}
+
+func TestNetworkPathService_CacheProfileChange(t *testing.T) {
+	profilePath := filepath.Join(t.TempDir(), "profile.yaml")
+	require.NoError(t, os.WriteFile(profilePath, []byte("name: v1"), 0644))
+	opts := models.ImportOptions{Profile: profilePath}
+
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "custom"}
+	mockRepo.On("LoadProfile", profilePath).Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Return(nil).Once()
+	service := &NetworkPathService{Repository: mockRepo, Cache: ordercache.NewOrderCache(config.CacheConfig{MaxEntries: 4})}
+	workbook := newWorkbook("PO.xlsx", "v1")
+	workbook.ModTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	unread := models.Workbook{Path: workbook.Path, Size: workbook.Size, ModTime: workbook.ModTime}
+
+	_, err := service.QueryOrders(workbook, opts, models.RequestQuery{})
+	require.NoError(t, err)
+	_, found := service.CachedOrders(unread, opts)
+	assert.True(t, found)
+
+	// Orders mapped with the profile before it was edited are not served
+	edited := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(profilePath, edited, edited))
+	_, found = service.CachedOrders(unread, opts)
+	assert.False(t, found)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNetworkPathService_StreamWhileCaching(t *testing.T) {
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}, {JobIDNo: stringPtr("J-002")}}
+	var streamed []models.PurchaseOrder
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		_ = fn(orders[0])
+		// The first order reaches the caller before the next one is parsed
+		assert.Len(t, streamed, 1)
+		_ = fn(orders[1])
+	}).Return(nil).Once()
+
+	service := &NetworkPathService{Repository: mockRepo, Cache: ordercache.NewOrderCache(config.CacheConfig{MaxEntries: 4})}
+	workbook := newWorkbook("PO.xlsx", "v1")
+
+	// A failing caller stops getting orders, but the whole workbook is cached
+	err := service.StreamOrders(workbook, models.ImportOptions{}, models.RequestQuery{}, func(order models.PurchaseOrder) error {
+		streamed = append(streamed, order)
+		if len(streamed) == 1 {
+			return errors.New("client gone")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "client gone")
+
+	page, err := service.QueryOrders(workbook, models.ImportOptions{}, models.RequestQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, orders, page.Orders)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNetworkPathService_ConcurrentImports(t *testing.T) {
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}}
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(func(args mock.Arguments) {
+		// Hold the parse until every import has asked for it
+		close(started)
+		<-release
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}).Return(nil).Once()
+
+	service := &NetworkPathService{Repository: mockRepo, Cache: ordercache.NewOrderCache(config.CacheConfig{MaxEntries: 4})}
+	workbook := newWorkbook("PO.xlsx", "v1")
+
+	var wg sync.WaitGroup
+	pages := make([]models.OrderPage, 10)
+	errs := make([]error, 10)
+	query := func(i int) {
+		defer wg.Done()
+		pages[i], errs[i] = service.QueryOrders(workbook, models.ImportOptions{}, models.RequestQuery{})
+	}
+	wg.Add(len(pages))
+	go query(0)
+	<-started
+	for i := 1; i < len(pages); i++ {
+		go query(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// The workbook was parsed once and every import got its orders
+	for i := range pages {
+		require.NoError(t, errs[i])
+		assert.Equal(t, orders, pages[i].Orders)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNetworkPathService_Snapshots(t *testing.T) {
+	store, err := orderstore.NewOrderStoreRepository(filepath.Join(t.TempDir(), "orders.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	service := &NetworkPathService{Repository: mockRepo, Store: store}
+
+	importVersion := func(content string, orders []models.PurchaseOrder) {
+		mockRepo.On("EachOrderFromReader", mock.Anything, profile, mock.Anything).Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(models.PurchaseOrder) error)
+			for _, order := range orders {
+				_ = fn(order)
+			}
+		}).Return(nil).Once()
+		_, err := service.QueryOrders(newWorkbook(filePath, content), models.ImportOptions{}, models.RequestQuery{})
+		require.NoError(t, err)
+	}
+	importVersion("v1", []models.PurchaseOrder{{JobIDNo: stringPtr("J-001"), Received: intPtr(0)}, {JobIDNo: stringPtr("J-002")}})
+	importVersion("v1", []models.PurchaseOrder{{JobIDNo: stringPtr("J-001"), Received: intPtr(0)}, {JobIDNo: stringPtr("J-002")}})
+	importVersion("v2", []models.PurchaseOrder{{JobIDNo: stringPtr("J-001"), Received: intPtr(4)}, {JobIDNo: stringPtr("J-003")}})
+
+	// The unchanged second import is not a new snapshot
+	snapshots, err := service.ListSnapshots(filePath)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, 2, snapshots[0].RowCount)
+	assert.NotEqual(t, snapshots[0].ContentHash, snapshots[1].ContentHash)
+
+	diff, err := service.DiffSnapshots(snapshots[1].ID, snapshots[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, snapshots[1], diff.From)
+	assert.Equal(t, snapshots[0], diff.To)
+	assert.Equal(t, []models.PurchaseOrder{{JobIDNo: stringPtr("J-003")}}, diff.Added)
+	assert.Equal(t, []models.PurchaseOrder{{JobIDNo: stringPtr("J-002")}}, diff.Removed)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, []models.FieldChange{{Field: "received", From: 0, To: 4}}, diff.Changed[0].Fields)
+
+	_, err = service.DiffSnapshots(snapshots[1].ID, 999)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+
+	withoutStore := &NetworkPathService{Repository: mockRepo}
+	_, err = withoutStore.ListSnapshots("")
+	assert.ErrorIs(t, err, ErrStoreDisabled)
+	_, err = withoutStore.DiffSnapshots(1, 2)
+	assert.ErrorIs(t, err, ErrStoreDisabled)
+}
+
+func TestNetworkPathService_ImportOrdersFromReader(t *testing.T) {
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}, {JobIDNo: stringPtr("J-002")}}
+	reader := strings.NewReader("workbook")
+
+	mockRepo := new(mocks.INetworkPathRepository)
+	profile := &models.ImportProfile{Name: "default"}
+	mockRepo.On("LoadProfile", "").Return(profile, nil)
+	mockRepo.On("EachOrderFromReader", reader, profile, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PurchaseOrder) error)
+		for _, order := range orders {
+			_ = fn(order)
+		}
+	}).Return(nil)
+
+	service := &NetworkPathService{Repository: mockRepo}
+
+	result, err := service.ImportOrdersFromReader(reader, models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, orders, result)
+	assert.Equal(t, "PO", profile.SheetName)
+	mockRepo.AssertExpectations(t)
+}
+
+// newWorkbook returns a workbook read from path with the given content
+func newWorkbook(path, content string) models.Workbook {
+	sum := sha256.Sum256([]byte(content))
+	return models.Workbook{Path: path, Size: int64(len(content)), Hash: hex.EncodeToString(sum[:]), Data: []byte(content)}
+}
+
 // Helper functions to create pointers for string and int values
 func stringPtr(s string) *string {
 	return &s
@@ -167,3 +641,7 @@ func stringPtr(s string) *string {
 func intPtr(i int) *int {
 	return &i
 }
+
+func datePtr(s string) *models.Date {
+	return utils.DateOrNil(s)
+}
@@ -1,14 +1,13 @@
 package importexcel
 
 import (
-	"fmt"
 	"purchase-record/internal/models"
 
 	"github.com/xuri/excelize/v2"
 )
 
 type ISettingPathRepository interface {
-	GetSettingPath(filePath string) ([]models.SettingExcelData, error)
+	GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error)
 }
 
 type SettingPathRepository struct{}
@@ -17,19 +16,19 @@ func NewSettingPathRepository() ISettingPathRepository {
 	return &SettingPathRepository{}
 }
 
-func (r *SettingPathRepository) GetSettingPath(filePath string) ([]models.SettingExcelData, error) {
+func (r *SettingPathRepository) GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error) {
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	sheets := f.GetSheetList()
-	if len(sheets) == 0 {
-		return nil, fmt.Errorf("no sheets found in Excel file")
+	// Settings live on the first sheet unless the caller picks another
+	sheetName, err := selectSheet(f, sheet, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	sheetName := sheets[0]
 	rows, err := f.GetRows(sheetName)
 	if err != nil {
 		return nil, err
@@ -47,6 +46,10 @@ func (r *SettingPathRepository) GetSettingPath(filePath string) ([]models.Settin
 			Path: row[0],
 			Name: row[1],
 		}
+		// Optional third column names the data sheet to import for this path
+		if len(row) > 2 {
+			setting.Sheet = row[2]
+		}
 		settings = append(settings, setting)
 	}
 
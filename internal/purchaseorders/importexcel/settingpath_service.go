@@ -1,9 +1,21 @@
 package importexcel
 
-import "purchase-record/internal/models"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"purchase-record/internal/models"
+)
+
+// ErrSettingsNotConfigured is returned when no settings workbook path is given
+var ErrSettingsNotConfigured = errors.New("the settings workbook is not configured")
+
+// ErrSettingsUnreachable is returned when the settings workbook cannot be
+// found or accessed, such as while its network share is down
+var ErrSettingsUnreachable = errors.New("the settings workbook is unreachable")
 
 type ISettingPathService interface {
-	GetSettingPath(filePath string) ([]models.SettingExcelData, error)
+	GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error)
 }
 
 type SettingPathService struct {
@@ -16,6 +28,19 @@ func NewSettingPathService() ISettingPathService {
 	}
 }
 
-func (s *SettingPathService) GetSettingPath(filePath string) ([]models.SettingExcelData, error) {
-	return s.Repository.GetSettingPath(filePath)
+// GetSettingPath reads the paths listed in the settings workbook at filePath.
+// An empty path fails with ErrSettingsNotConfigured, and a file that cannot be
+// reached with ErrSettingsUnreachable wrapping the cause.
+func (s *SettingPathService) GetSettingPath(filePath string, sheet models.SheetSelector) ([]models.SettingExcelData, error) {
+	if filePath == "" {
+		return nil, ErrSettingsNotConfigured
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSettingsUnreachable, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("settings workbook '%s' is a directory", filePath)
+	}
+	return s.Repository.GetSettingPath(filePath, sheet)
 }
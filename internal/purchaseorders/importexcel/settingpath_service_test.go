@@ -0,0 +1,41 @@
+package importexcel
+
+import (
+	"io/fs"
+	"path/filepath"
+	"purchase-record/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSettingPathService_GetSettingPath(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "settings.xlsx")
+	f := excelize.NewFile()
+	for i, row := range [][]any{{"Path", "Name", "Sheet"}, {`\\share\PO.xlsx`, "Orders", "PO"}, {"incomplete"}} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	require.NoError(t, f.SaveAs(filePath))
+	require.NoError(t, f.Close())
+	service := NewSettingPathService()
+
+	settings, err := service.GetSettingPath(filePath, models.SheetSelector{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SettingExcelData{{Path: `\\share\PO.xlsx`, Name: "Orders", Sheet: "PO"}}, settings)
+
+	_, err = service.GetSettingPath("", models.SheetSelector{})
+	assert.ErrorIs(t, err, ErrSettingsNotConfigured)
+
+	// The cause of an unreachable workbook is kept for diagnostics
+	_, err = service.GetSettingPath(filepath.Join(dir, "missing.xlsx"), models.SheetSelector{})
+	assert.ErrorIs(t, err, ErrSettingsUnreachable)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = service.GetSettingPath(dir, models.SheetSelector{})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrSettingsUnreachable)
+}
@@ -0,0 +1,107 @@
+package importexcel
+
+import (
+	"fmt"
+	"purchase-record/internal/models"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// selectSheet resolves a selector against the workbook's sheet list. An empty
+// selector picks the sheet at defaultIndex.
+func selectSheet(f *excelize.File, selector models.SheetSelector, defaultIndex int) (string, error) {
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("no sheets found in Excel file")
+	}
+
+	switch {
+	case selector.Sheet != "":
+		for _, name := range sheets {
+			if name == selector.Sheet {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("sheet '%s' not found in Excel file, available sheets: %s", selector.Sheet, strings.Join(sheets, ", "))
+	case selector.SheetPattern != "":
+		pattern, err := regexp.Compile(selector.SheetPattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid sheet pattern '%s': %w", selector.SheetPattern, err)
+		}
+		for _, name := range sheets {
+			if pattern.MatchString(name) {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("no sheet matching '%s' found in Excel file, available sheets: %s", selector.SheetPattern, strings.Join(sheets, ", "))
+	}
+
+	index := defaultIndex
+	if selector.SheetIndex != nil {
+		index = *selector.SheetIndex
+	}
+	if index < 0 || index >= len(sheets) {
+		return "", fmt.Errorf("sheet at index %d not found in Excel file", index)
+	}
+	return sheets[index], nil
+}
+
+// profileSheetSelector returns the sheet selection stored in a profile.
+func profileSheetSelector(profile *models.ImportProfile) models.SheetSelector {
+	index := profile.SheetIndex
+	return models.SheetSelector{
+		Sheet:        profile.SheetName,
+		SheetPattern: profile.SheetPattern,
+		SheetIndex:   &index,
+	}
+}
+
+// applySheetSelector replaces the profile's sheet selection with selector
+// when the caller made one.
+func applySheetSelector(profile *models.ImportProfile, selector models.SheetSelector) {
+	if selector.IsEmpty() {
+		return
+	}
+	profile.SheetName = selector.Sheet
+	profile.SheetPattern = selector.SheetPattern
+	if selector.SheetIndex != nil {
+		profile.SheetIndex = *selector.SheetIndex
+	}
+}
+
+// listSheets reports every sheet of the workbook with the size of its used range.
+func listSheets(f *excelize.File) ([]models.SheetInfo, error) {
+	sheets := f.GetSheetList()
+	infos := make([]models.SheetInfo, 0, len(sheets))
+	for i, name := range sheets {
+		rows, err := f.Rows(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rows from sheet '%s': %w", name, err)
+		}
+
+		info := models.SheetInfo{Index: i, Name: name}
+		for rows.Next() {
+			cols, err := rows.Columns()
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read rows from sheet '%s': %w", name, err)
+			}
+			info.Rows++
+			info.Columns = max(info.Columns, len(cols))
+		}
+		if err := rows.Close(); err != nil {
+			return nil, fmt.Errorf("failed to read rows from sheet '%s': %w", name, err)
+		}
+
+		visible, err := f.GetSheetVisible(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read visibility of sheet '%s': %w", name, err)
+		}
+		info.Visible = visible
+
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
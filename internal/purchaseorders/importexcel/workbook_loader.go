@@ -0,0 +1,79 @@
+package importexcel
+
+import (
+	"bytes"
+	"os"
+	"purchase-record/internal/models"
+	"purchase-record/internal/utils"
+	"time"
+)
+
+// WorkbookLoader prepares a workbook for import the same way whether a request
+// or the watcher asks for it: the file is read into memory once, unless its
+// orders are cached for its current size and modification time, and those
+// bytes are backed up when they prove to be a readable workbook with the sheet
+// and headers the options expect.
+type WorkbookLoader struct {
+	Service INetworkPathService
+	// MaxSourceSize is the largest workbook read into memory in bytes, 0 for
+	// no limit
+	MaxSourceSize int64
+}
+
+// LoadedWorkbook is a workbook ready to be parsed by the service
+type LoadedWorkbook struct {
+	Workbook models.Workbook
+	// Cached is set when the workbook's cached orders were taken instead of
+	// reading it, in which case it was not backed up either
+	Cached bool
+	// BackupError is why the workbook was not backed up; the previous backup
+	// is kept
+	BackupError string
+	// ReadTime and BackupTime are how long each step took
+	ReadTime   time.Duration
+	BackupTime time.Duration
+}
+
+// NewWorkbookLoader loads workbooks of up to maxSourceSize bytes for service
+func NewWorkbookLoader(service INetworkPathService, maxSourceSize int64) *WorkbookLoader {
+	return &WorkbookLoader{Service: service, MaxSourceSize: maxSourceSize}
+}
+
+// Load reads the workbook at filePath for import with opts, backing it up on
+// the way. A failed backup does not stop the import and is reported in the
+// result. The error of a file that cannot be read, such as
+// utils.ErrSourceTooLarge, is returned as it is along with the time spent.
+func (l *WorkbookLoader) Load(filePath string, opts models.ImportOptions) (LoadedWorkbook, error) {
+	start := time.Now()
+	if workbook, cached := l.cachedWorkbook(filePath, opts); cached {
+		return LoadedWorkbook{Workbook: workbook, Cached: true, ReadTime: time.Since(start)}, nil
+	}
+	workbook, err := utils.ReadSource(filePath, l.MaxSourceSize)
+	loaded := LoadedWorkbook{Workbook: workbook, ReadTime: time.Since(start)}
+	if err != nil {
+		return loaded, err
+	}
+
+	start = time.Now()
+	_, err = utils.BackupFile(filePath, workbook.Data, func(data []byte) error {
+		return l.Service.ValidateWorkbook(bytes.NewReader(data), opts)
+	})
+	loaded.BackupTime = time.Since(start)
+	if err != nil {
+		loaded.BackupError = err.Error()
+	}
+	return loaded, nil
+}
+
+// cachedWorkbook describes the file at filePath without reading it, along
+// with its orders when the service has them cached
+func (l *WorkbookLoader) cachedWorkbook(filePath string, opts models.ImportOptions) (models.Workbook, bool) {
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return models.Workbook{}, false
+	}
+	workbook := models.Workbook{Path: filePath, Size: info.Size(), ModTime: info.ModTime()}
+	orders, found := l.Service.CachedOrders(workbook, opts)
+	workbook.Orders = orders
+	return workbook, found
+}
@@ -0,0 +1,195 @@
+package ordercache
+
+import (
+	"purchase-record/config"
+	"purchase-record/internal/models"
+	"sync"
+	"time"
+)
+
+// Key identifies the orders of one workbook parsed one way
+type Key struct {
+	// Path is the file that was read
+	Path string
+	// Source is the location the file stands for, the same as Path unless
+	// the file is a backup
+	Source string
+	// Options describes the profile and sheet the workbook was parsed with
+	Options string
+}
+
+// IOrderCache keeps the orders of recently parsed workbooks so an unchanged
+// workbook is not parsed again. Cached orders are shared between callers and
+// must not be modified.
+type IOrderCache interface {
+	Get(key Key, workbook models.Workbook) ([]models.PurchaseOrder, bool)
+	Fresh(key Key, workbook models.Workbook) ([]models.PurchaseOrder, bool)
+	Put(key Key, workbook models.Workbook, orders []models.PurchaseOrder)
+	Invalidate(path string) int
+	Stats() models.CacheStats
+}
+
+type entry struct {
+	size     int64
+	modTime  time.Time
+	hash     string
+	orders   []models.PurchaseOrder
+	storedAt time.Time
+	usedAt   time.Time
+}
+
+// OrderCache holds up to MaxEntries workbooks for up to TTL each, dropping the
+// least recently used one when it is full
+type OrderCache struct {
+	MaxEntries int
+	// TTL is how long an entry is kept after it was parsed, 0 for no limit
+	TTL time.Duration
+	Now func() time.Time
+
+	mutex   sync.Mutex
+	entries map[Key]*entry
+	stats   models.CacheStats
+}
+
+// NewOrderCache applies the cache configuration. It returns nil when the
+// configuration allows no entries, which disables caching.
+func NewOrderCache(cfg config.CacheConfig) IOrderCache {
+	if cfg.MaxEntries <= 0 {
+		return nil
+	}
+	return &OrderCache{MaxEntries: cfg.MaxEntries, TTL: cfg.TTL, Now: time.Now}
+}
+
+// Get returns the orders cached under key when the workbook has the same size
+// and modification time as when they were cached, or failing that the same
+// content hash. Every call counts as a hit or a miss.
+func (c *OrderCache) Get(key Key, workbook models.Workbook) ([]models.PurchaseOrder, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached := c.lookup(key)
+	switch {
+	case cached == nil:
+	case sameFile(cached, workbook):
+	case workbook.Loaded() && workbook.Hash == cached.hash:
+		// Copied or touched but unchanged, so later lookups match by file again
+		cached.size, cached.modTime = workbook.Size, workbook.ModTime
+	default:
+		cached = nil
+	}
+
+	if cached == nil {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	cached.usedAt = c.Now()
+	return cached.orders, true
+}
+
+// Fresh returns the orders cached under key when the workbook has the same
+// size and modification time, so a file need not be read to be served. A
+// match counts as a hit, but no miss is counted since the caller goes on to
+// read the file and Get it.
+func (c *OrderCache) Fresh(key Key, workbook models.Workbook) ([]models.PurchaseOrder, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached := c.lookup(key)
+	if cached == nil || !sameFile(cached, workbook) {
+		return nil, false
+	}
+	c.stats.Hits++
+	cached.usedAt = c.Now()
+	return cached.orders, true
+}
+
+// Put caches the orders parsed from workbook under key
+func (c *OrderCache) Put(key Key, workbook models.Workbook, orders []models.PurchaseOrder) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[Key]*entry{}
+	}
+	now := c.Now()
+	if _, found := c.entries[key]; !found && len(c.entries) >= c.MaxEntries {
+		c.evictOldest()
+	}
+	c.entries[key] = &entry{
+		size:     workbook.Size,
+		modTime:  workbook.ModTime,
+		hash:     workbook.Hash,
+		orders:   orders,
+		storedAt: now,
+		usedAt:   now,
+	}
+}
+
+// Invalidate drops the entries read from or standing for path, or every entry
+// when path is empty, and returns how many were dropped
+func (c *OrderCache) Invalidate(path string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for key := range c.entries {
+		if path == "" || key.Path == path || key.Source == path {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	c.stats.Evictions += int64(removed)
+	return removed
+}
+
+// Stats returns the hits, misses and evictions so far with the current size
+func (c *OrderCache) Stats() models.CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Expired entries are not worth reporting
+	for key := range c.entries {
+		c.lookup(key)
+	}
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.MaxEntries = c.MaxEntries
+	stats.TTLSeconds = int64(c.TTL / time.Second)
+	return stats
+}
+
+// lookup returns the entry of key, dropping it when it has expired
+func (c *OrderCache) lookup(key Key) *entry {
+	cached, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	if c.TTL > 0 && c.Now().Sub(cached.storedAt) > c.TTL {
+		delete(c.entries, key)
+		c.stats.Evictions++
+		return nil
+	}
+	return cached
+}
+
+// evictOldest drops the least recently used entry
+func (c *OrderCache) evictOldest() {
+	var oldest Key
+	var oldestEntry *entry
+	for key, cached := range c.entries {
+		if oldestEntry == nil || cached.usedAt.Before(oldestEntry.usedAt) {
+			oldest, oldestEntry = key, cached
+		}
+	}
+	if oldestEntry != nil {
+		delete(c.entries, oldest)
+		c.stats.Evictions++
+	}
+}
+
+// sameFile reports whether workbook has the size and modification time of the
+// cached entry. A workbook without a modification time never matches.
+func sameFile(cached *entry, workbook models.Workbook) bool {
+	return !workbook.ModTime.IsZero() && workbook.Size == cached.size && workbook.ModTime.Equal(cached.modTime)
+}
@@ -0,0 +1,115 @@
+package ordercache
+
+import (
+	"purchase-record/config"
+	"purchase-record/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPtr(s string) *string { return &s }
+
+var modTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestCache(maxEntries int, ttl time.Duration) (*OrderCache, *time.Time) {
+	now := modTime
+	return &OrderCache{MaxEntries: maxEntries, TTL: ttl, Now: func() time.Time { return now }}, &now
+}
+
+func workbook(path, hash string, size int64, modified time.Time) models.Workbook {
+	return models.Workbook{Path: path, Size: size, ModTime: modified, Hash: hash, Data: []byte(hash)}
+}
+
+// fresh reports whether the cache serves workbook without reading it
+func fresh(cache *OrderCache, key Key, workbook models.Workbook) bool {
+	_, found := cache.Fresh(key, workbook)
+	return found
+}
+
+func TestOrderCache_Get(t *testing.T) {
+	cache, _ := newTestCache(4, 0)
+	key := Key{Path: "PO.xlsx", Source: "PO.xlsx"}
+	orders := []models.PurchaseOrder{{JobIDNo: stringPtr("J-001")}}
+
+	_, found := cache.Get(key, workbook("PO.xlsx", "hash-1", 10, modTime))
+	assert.False(t, found)
+	cache.Put(key, workbook("PO.xlsx", "hash-1", 10, modTime), orders)
+
+	// Matched by size and modification time without reading the file
+	stat := models.Workbook{Path: "PO.xlsx", Size: 10, ModTime: modTime}
+	cached, found := cache.Fresh(key, stat)
+	assert.True(t, found)
+	assert.Equal(t, orders, cached)
+	cached, found = cache.Get(key, stat)
+	assert.True(t, found)
+	assert.Equal(t, orders, cached)
+
+	// A touched file with the same content is confirmed by its hash
+	touched := workbook("PO.xlsx", "hash-1", 10, modTime.Add(time.Hour))
+	assert.False(t, fresh(cache, key, touched))
+	_, found = cache.Get(key, touched)
+	assert.True(t, found)
+	assert.True(t, fresh(cache, key, touched))
+
+	// Changed content is a miss
+	_, found = cache.Get(key, workbook("PO.xlsx", "hash-2", 12, modTime.Add(2*time.Hour)))
+	assert.False(t, found)
+
+	// Other parse options are other entries
+	_, found = cache.Get(Key{Path: "PO.xlsx", Source: "PO.xlsx", Options: "profile.yaml"}, stat)
+	assert.False(t, found)
+
+	assert.Equal(t, models.CacheStats{Entries: 1, MaxEntries: 4, Hits: 4, Misses: 3}, cache.Stats())
+}
+
+func TestOrderCache_Limits(t *testing.T) {
+	cache, now := newTestCache(2, time.Minute)
+	keyA, keyB, keyC := Key{Path: "a.xlsx"}, Key{Path: "b.xlsx"}, Key{Path: "c.xlsx"}
+	a, b, c := workbook("a.xlsx", "a", 1, modTime), workbook("b.xlsx", "b", 1, modTime), workbook("c.xlsx", "c", 1, modTime)
+
+	cache.Put(keyA, a, nil)
+	*now = now.Add(time.Second)
+	cache.Put(keyB, b, nil)
+	*now = now.Add(time.Second)
+
+	// Using A leaves B as the least recently used entry
+	_, found := cache.Get(keyA, a)
+	assert.True(t, found)
+	cache.Put(keyC, c, nil)
+	assert.False(t, fresh(cache, keyB, b))
+	assert.True(t, fresh(cache, keyA, a))
+	assert.True(t, fresh(cache, keyC, c))
+
+	// Entries expire a TTL after they were parsed, however often they are used
+	*now = now.Add(time.Minute)
+	assert.False(t, fresh(cache, keyA, a))
+	assert.True(t, fresh(cache, keyC, c))
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(2), stats.Evictions)
+	assert.Equal(t, int64(60), stats.TTLSeconds)
+}
+
+func TestOrderCache_Invalidate(t *testing.T) {
+	cache, _ := newTestCache(4, 0)
+	backup := Key{Path: "backup/PO_20240501.xlsx", Source: `\\share\PO.xlsx`}
+	other := Key{Path: "other.xlsx", Source: "other.xlsx"}
+	cache.Put(backup, workbook(backup.Path, "a", 1, modTime), nil)
+	cache.Put(other, workbook(other.Path, "b", 1, modTime), nil)
+
+	// A backup is dropped along with the source it stands for
+	assert.Equal(t, 1, cache.Invalidate(`\\share\PO.xlsx`))
+	assert.Equal(t, 0, cache.Invalidate(`\\share\PO.xlsx`))
+	assert.Equal(t, 1, cache.Invalidate(""))
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestNewOrderCache(t *testing.T) {
+	assert.Nil(t, NewOrderCache(config.CacheConfig{}))
+
+	cache := NewOrderCache(config.CacheConfig{MaxEntries: 8, TTL: time.Minute})
+	assert.Equal(t, models.CacheStats{MaxEntries: 8, TTLSeconds: 60}, cache.Stats())
+}
@@ -0,0 +1,100 @@
+package orderdiff
+
+import (
+	"fmt"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderfields"
+	"strings"
+)
+
+// Diff compares two versions of a workbook's orders. Orders are matched by
+// Key; when several orders share a key they are matched in the order they
+// appear. Every field of orderfields is compared, so shipments and warnings
+// are only reported through delivery_log.
+func Diff(from, to []models.PurchaseOrder) models.OrderDiff {
+	diff := models.OrderDiff{
+		Added:   []models.PurchaseOrder{},
+		Removed: []models.PurchaseOrder{},
+		Changed: []models.OrderChange{},
+	}
+
+	fromKeys := keys(from)
+	previous := make(map[string]models.PurchaseOrder, len(from))
+	for i, key := range fromKeys {
+		previous[key] = from[i]
+	}
+
+	fields := orderfields.Fields()
+	for i, key := range keys(to) {
+		order := to[i]
+		old, found := previous[key]
+		if !found {
+			diff.Added = append(diff.Added, order)
+			continue
+		}
+		delete(previous, key)
+
+		if changes := changedFields(fields, old, order); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.OrderChange{Key: key, Order: order, Fields: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+
+	// Whatever was not matched is gone, reported in its original order
+	for i, key := range fromKeys {
+		if _, left := previous[key]; left {
+			diff.Removed = append(diff.Removed, from[i])
+		}
+	}
+	return diff
+}
+
+// Key identifies an order line by its job ID and product code, such as
+// "J-001/P-100".
+func Key(order models.PurchaseOrder) string {
+	return value(order.JobIDNo) + "/" + value(order.ProductCode)
+}
+
+// keys returns the key of every order, numbering repeated keys as
+// "J-001/P-100#2" so each key is unique.
+func keys(orders []models.PurchaseOrder) []string {
+	seen := make(map[string]int, len(orders))
+	result := make([]string, len(orders))
+	for i, order := range orders {
+		key := Key(order)
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		result[i] = key
+	}
+	return result
+}
+
+func changedFields(fields []orderfields.Field, from, to models.PurchaseOrder) []models.FieldChange {
+	var changes []models.FieldChange
+	for _, field := range fields {
+		old, current := field.Value(from), field.Value(to)
+		if !equal(old, current) {
+			changes = append(changes, models.FieldChange{Field: field.Name, From: old, To: current})
+		}
+	}
+	return changes
+}
+
+// equal compares two field values, treating dates as equal by calendar day
+func equal(a, b any) bool {
+	if dateA, ok := a.(models.Date); ok {
+		dateB, ok := b.(models.Date)
+		return ok && dateA.String() == dateB.String()
+	}
+	return a == b
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
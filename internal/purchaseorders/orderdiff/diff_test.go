@@ -0,0 +1,78 @@
+package orderdiff
+
+import (
+	"purchase-record/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPtr(s string) *string { return &s }
+func intPtr(i int) *int          { return &i }
+
+func datePtr(value string) *models.Date {
+	t, _ := time.Parse(models.DateLayout, value)
+	date := models.NewDate(t)
+	return &date
+}
+
+func TestDiff(t *testing.T) {
+	from := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-1"), ProductCode: stringPtr("P-1"), Ordered: intPtr(10), Received: intPtr(0), Status: stringPtr("Not Started")},
+		{JobIDNo: stringPtr("J-1"), ProductCode: stringPtr("P-2"), Ordered: intPtr(5), DeliveryDate: datePtr("2024-03-01")},
+		{JobIDNo: stringPtr("J-2"), ProductCode: stringPtr("P-1"), Ordered: intPtr(1)},
+	}
+	to := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-1"), ProductCode: stringPtr("P-2"), Ordered: intPtr(5), DeliveryDate: datePtr("2024-03-01")},
+		{JobIDNo: stringPtr("J-1"), ProductCode: stringPtr("P-1"), Ordered: intPtr(10), Received: intPtr(10), Status: stringPtr("Completed")},
+		{JobIDNo: stringPtr("J-3"), ProductCode: stringPtr("P-9"), Ordered: intPtr(2)},
+	}
+
+	diff := Diff(from, to)
+
+	assert.Equal(t, []models.PurchaseOrder{to[2]}, diff.Added)
+	assert.Equal(t, []models.PurchaseOrder{from[2]}, diff.Removed)
+	assert.Equal(t, 1, diff.Unchanged)
+	assert.Equal(t, []models.OrderChange{{
+		Key:   "J-1/P-1",
+		Order: to[1],
+		Fields: []models.FieldChange{
+			{Field: "received", From: 0, To: 10},
+			{Field: "status", From: "Not Started", To: "Completed"},
+		},
+	}}, diff.Changed)
+}
+
+func TestDiff_RepeatedKeys(t *testing.T) {
+	from := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-1"), Remark: stringPtr("first")},
+		{JobIDNo: stringPtr("J-1"), Remark: stringPtr("second")},
+	}
+	to := []models.PurchaseOrder{
+		{JobIDNo: stringPtr("J-1"), Remark: stringPtr("first")},
+		{JobIDNo: stringPtr("J-1"), Remark: nil},
+		{JobIDNo: stringPtr("J-1"), Remark: stringPtr("third")},
+	}
+
+	diff := Diff(from, to)
+
+	assert.Equal(t, []models.PurchaseOrder{to[2]}, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, 1, diff.Unchanged)
+	assert.Equal(t, []models.OrderChange{{
+		Key:    "J-1/#2",
+		Order:  to[1],
+		Fields: []models.FieldChange{{Field: "remark", From: "second", To: nil}},
+	}}, diff.Changed)
+}
+
+func TestDiff_Empty(t *testing.T) {
+	diff := Diff(nil, nil)
+
+	assert.Equal(t, models.OrderDiff{
+		Added:   []models.PurchaseOrder{},
+		Removed: []models.PurchaseOrder{},
+		Changed: []models.OrderChange{},
+	}, diff)
+}
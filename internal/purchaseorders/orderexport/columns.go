@@ -0,0 +1,78 @@
+package orderexport
+
+import (
+	"fmt"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderfields"
+	"slices"
+	"strings"
+)
+
+// Column is an exportable PurchaseOrder field. Field is its JSON name and
+// Title a readable heading such as "PO Receive Date".
+type Column struct {
+	Field string
+	Title string
+	field orderfields.Field
+}
+
+// titleWords are written in capitals in column titles
+var titleWords = map[string]string{"id": "ID", "pr": "PR", "po": "PO"}
+
+// columns lists every field of orderfields, in its order.
+var columns = func() []Column {
+	var result []Column
+	for _, field := range orderfields.Fields() {
+		result = append(result, Column{Field: field.Name, Title: columnTitle(field.Name), field: field})
+	}
+	return result
+}()
+
+// Columns returns every exportable column.
+func Columns() []Column {
+	return append([]Column(nil), columns...)
+}
+
+// SelectColumns returns the named columns in the given order, or every
+// column when no names are given. Each name may also list several columns
+// separated by commas.
+func SelectColumns(fields []string) ([]Column, error) {
+	var selected []Column
+	for _, list := range fields {
+		for _, field := range strings.Split(list, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			index := slices.IndexFunc(columns, func(c Column) bool { return c.Field == field })
+			if index < 0 {
+				return nil, fmt.Errorf("cannot export unknown column %q", field)
+			}
+			selected = append(selected, columns[index])
+		}
+	}
+
+	if len(selected) == 0 {
+		return Columns(), nil
+	}
+	return selected, nil
+}
+
+// columnTitle turns "po_receive_date" into "PO Receive Date"
+func columnTitle(field string) string {
+	words := strings.Split(field, "_")
+	for i, word := range words {
+		if upper, ok := titleWords[word]; ok {
+			words[i] = upper
+		} else if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Value returns the column of order as a string, an int or a models.Date,
+// or nil when it is empty.
+func (c Column) Value(order models.PurchaseOrder) any {
+	return c.field.Value(order)
+}
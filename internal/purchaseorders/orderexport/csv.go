@@ -0,0 +1,81 @@
+package orderexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"purchase-record/internal/models"
+	"strconv"
+	"unicode/utf8"
+)
+
+// utf8BOM lets Excel recognise the file as UTF-8 so Thai text is not garbled
+const utf8BOM = "\ufeff"
+
+type CSVExporter struct {
+	buffer  *bufio.Writer
+	writer  *csv.Writer
+	columns []Column
+	record  []string
+}
+
+// NewCSVExporter writes a header row of JSON field names, optionally preceded
+// by a UTF-8 byte order mark. Output is buffered, so nothing reaches w until
+// the buffer fills or Finish is called.
+func NewCSVExporter(w io.Writer, columns []Column, delimiter rune, bom bool) (IExporter, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+	// csv.Writer only reports a bad delimiter once it writes
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError || delimiter == 0 {
+		return nil, fmt.Errorf("%q cannot be used as a delimiter", delimiter)
+	}
+
+	buffer := bufio.NewWriter(w)
+	if bom {
+		_, _ = buffer.WriteString(utf8BOM)
+	}
+	writer := csv.NewWriter(buffer)
+	writer.Comma = delimiter
+
+	e := &CSVExporter{buffer: buffer, writer: writer, columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		e.record[i] = column.Field
+	}
+	if err := writer.Write(e.record); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Write adds the order as the next row. Dates are written as YYYY-MM-DD and
+// empty fields as empty cells.
+func (e *CSVExporter) Write(order models.PurchaseOrder) error {
+	for i, column := range e.columns {
+		switch value := column.Value(order).(type) {
+		case nil:
+			e.record[i] = ""
+		case string:
+			e.record[i] = value
+		case int:
+			e.record[i] = strconv.Itoa(value)
+		case models.Date:
+			e.record[i] = value.String()
+		}
+	}
+	return e.writer.Write(e.record)
+}
+
+// Finish writes any buffered rows.
+func (e *CSVExporter) Finish() error {
+	e.writer.Flush()
+	if err := e.writer.Error(); err != nil {
+		return err
+	}
+	return e.buffer.Flush()
+}
+
+func (e *CSVExporter) Close() error {
+	return nil
+}
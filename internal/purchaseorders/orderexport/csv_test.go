@@ -0,0 +1,80 @@
+package orderexport
+
+import (
+	"bytes"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExporter_CSV(t *testing.T) {
+	text := func(s string) *string { return &s }
+	ordered := 1200
+	orders := []models.PurchaseOrder{
+		{JobIDNo: text("J-001"), Customer: text("บริษัท เอ, จำกัด"), Ordered: &ordered, PODate: utils.DateOrNil("2024-03-12")},
+		{JobIDNo: text("J-002"), Remark: text(`say "hi"`)},
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "csv with byte order mark",
+			opts:     Options{Format: FormatCSV, Columns: []string{"job_id_no,customer", "ordered", "po_date"}, BOM: true},
+			expected: "\ufeffjob_id_no,customer,ordered,po_date\nJ-001,\"บริษัท เอ, จำกัด\",1200,2024-03-12\nJ-002,,,\n",
+		},
+		{
+			name:     "csv with custom delimiter",
+			opts:     Options{Format: FormatCSV, Columns: []string{"job_id_no", "remark"}, Delimiter: ";"},
+			expected: "job_id_no;remark\nJ-001;\nJ-002;\"say \"\"hi\"\"\"\n",
+		},
+		{
+			name:     "tsv ignores the delimiter option",
+			opts:     Options{Format: FormatTSV, Columns: []string{"job_id_no", "ordered"}, Delimiter: ";"},
+			expected: "job_id_no\tordered\nJ-001\t1200\nJ-002\t\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			exporter, err := NewExporter(&buf, tt.opts)
+			assert.NoError(t, err)
+			defer exporter.Close()
+
+			for _, order := range orders {
+				assert.NoError(t, exporter.Write(order))
+			}
+			// Nothing is written until the buffer fills or the export finishes
+			assert.Zero(t, buf.Len())
+			assert.NoError(t, exporter.Finish())
+
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	assert.NoError(t, ValidateOptions(Options{}))
+	assert.EqualError(t, ValidateOptions(Options{Format: "pdf"}), "format must be one of xlsx, csv or tsv")
+	assert.EqualError(t, ValidateOptions(Options{Columns: []string{"job_id_no,price"}}), `cannot export unknown column "price"`)
+	assert.EqualError(t, ValidateOptions(Options{Format: FormatCSV, Delimiter: "||"}), "delimiter must be a single character")
+
+	_, err := NewExporter(&bytes.Buffer{}, Options{Format: FormatCSV, Delimiter: `"`})
+	assert.EqualError(t, err, `'"' cannot be used as a delimiter`)
+}
+
+func TestSelectColumns(t *testing.T) {
+	all, err := SelectColumns(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Columns(), all)
+
+	selected, err := SelectColumns([]string{" status , job_id_no", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"status", "job_id_no"}, []string{selected[0].Field, selected[1].Field})
+	assert.Equal(t, "Status", selected[0].Title)
+}
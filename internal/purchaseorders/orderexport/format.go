@@ -0,0 +1,84 @@
+package orderexport
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Export formats
+const (
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+)
+
+// Options choose the format and columns of an export. Delimiter and BOM only
+// apply to CSV; TSV always uses tabs.
+type Options struct {
+	Format    string
+	Columns   []string
+	Delimiter string
+	BOM       bool
+}
+
+// formats holds the content type and the delimiter of each format
+var formats = map[string]struct {
+	contentType string
+	delimiter   rune
+}{
+	FormatXLSX: {contentType: MIMEXLSX},
+	FormatCSV:  {contentType: "text/csv; charset=utf-8", delimiter: ','},
+	FormatTSV:  {contentType: "text/tab-separated-values; charset=utf-8", delimiter: '\t'},
+}
+
+// ValidateOptions rejects unknown formats and columns and delimiters that
+// are not a single character.
+func ValidateOptions(opts Options) error {
+	if _, ok := formats[formatOrDefault(opts.Format)]; !ok {
+		return fmt.Errorf("format must be one of %s, %s or %s", FormatXLSX, FormatCSV, FormatTSV)
+	}
+	if _, err := SelectColumns(opts.Columns); err != nil {
+		return err
+	}
+	if opts.Delimiter != "" && utf8.RuneCountInString(opts.Delimiter) != 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	return nil
+}
+
+// NewExporter creates the exporter for opts.Format, XLSX when it is empty.
+func NewExporter(w io.Writer, opts Options) (IExporter, error) {
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	columns, _ := SelectColumns(opts.Columns)
+
+	format := formatOrDefault(opts.Format)
+	if format == FormatXLSX {
+		return NewXLSXExporter(w, columns)
+	}
+
+	delimiter := formats[format].delimiter
+	if format == FormatCSV && opts.Delimiter != "" {
+		delimiter, _ = utf8.DecodeRuneInString(opts.Delimiter)
+	}
+	return NewCSVExporter(w, columns, delimiter, opts.BOM)
+}
+
+// ContentType returns the MIME type of an export format.
+func ContentType(format string) string {
+	return formats[formatOrDefault(format)].contentType
+}
+
+// FileExtension returns the file extension of an export format, without dot.
+func FileExtension(format string) string {
+	return formatOrDefault(format)
+}
+
+func formatOrDefault(format string) string {
+	if format == "" {
+		return FormatXLSX
+	}
+	return format
+}
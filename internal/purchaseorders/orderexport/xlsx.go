@@ -0,0 +1,187 @@
+package orderexport
+
+import (
+	"fmt"
+	"io"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/orderstatus"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MIMEXLSX is the content type of Excel workbooks
+const MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// SheetName is the name of the exported worksheet
+const SheetName = "Purchase Orders"
+
+// dateNumberFormat shows date cells the way the API writes dates
+const dateNumberFormat = "yyyy-mm-dd"
+
+// IExporter writes orders one at a time into a document. Finish completes the
+// document on the exporter's writer and Close releases its resources whether
+// or not Finish was called.
+type IExporter interface {
+	Write(order models.PurchaseOrder) error
+	Finish() error
+	Close() error
+}
+
+// statusFills colours the rows of each status, as font and fill colours
+var statusFills = []struct {
+	status, font, fill string
+}{
+	{orderstatus.Completed, "006100", "C6EFCE"},
+	{orderstatus.PartiallyDelivered, "9C5700", "FFEB9C"},
+	{orderstatus.OverDelivered, "7F3F00", "F8CBAD"},
+	{orderstatus.Overdue, "9C0006", "FFC7CE"},
+	{orderstatus.Cancelled, "595959", "D9D9D9"},
+}
+
+type XLSXExporter struct {
+	w         io.Writer
+	columns   []Column
+	file      *excelize.File
+	stream    *excelize.StreamWriter
+	dateStyle int
+	row       int
+}
+
+// NewXLSXExporter starts a workbook with a bold, frozen header row. Rows are
+// kept in a temporary file by excelize until Finish writes the workbook to w.
+func NewXLSXExporter(w io.Writer, columns []Column) (IExporter, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+
+	f := excelize.NewFile()
+	e := &XLSXExporter{w: w, columns: columns, file: f}
+	if err := e.start(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *XLSXExporter) start() error {
+	if err := e.file.SetSheetName(e.file.GetSheetName(0), SheetName); err != nil {
+		return err
+	}
+
+	headerStyle, err := e.file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDEBF7"}},
+	})
+	if err != nil {
+		return err
+	}
+	dateFormat := dateNumberFormat
+	e.dateStyle, err = e.file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+
+	if e.stream, err = e.file.NewStreamWriter(SheetName); err != nil {
+		return err
+	}
+	// Panes and widths must be set before the first row
+	if err := e.stream.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	if err := e.stream.SetColWidth(1, len(e.columns), 18); err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: column.Title}
+	}
+	e.row = 1
+	return e.stream.SetRow("A1", header)
+}
+
+// Write adds the order as the next row. Dates are written as Excel dates and
+// quantities as numbers.
+func (e *XLSXExporter) Write(order models.PurchaseOrder) error {
+	e.row++
+	values := make([]interface{}, len(e.columns))
+	for i, column := range e.columns {
+		switch value := column.Value(order).(type) {
+		case models.Date:
+			values[i] = excelize.Cell{StyleID: e.dateStyle, Value: value.Time}
+		default:
+			values[i] = value
+		}
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+// Finish adds the autofilter and the status colours over the written rows and
+// writes the workbook.
+func (e *XLSXExporter) Finish() error {
+	lastCell, err := excelize.CoordinatesToCellName(len(e.columns), max(e.row, 2))
+	if err != nil {
+		return err
+	}
+	if err := e.file.AutoFilter(SheetName, "A1:"+lastCell, nil); err != nil {
+		return err
+	}
+	if err := e.addStatusFormats(lastCell); err != nil {
+		return err
+	}
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	_, err = e.file.WriteTo(e.w)
+	return err
+}
+
+// addStatusFormats colours each data row by its status when the status
+// column is exported
+func (e *XLSXExporter) addStatusFormats(lastCell string) error {
+	statusColumn := 0
+	for i, column := range e.columns {
+		if column.Field == "status" {
+			statusColumn = i + 1
+		}
+	}
+	if statusColumn == 0 {
+		return nil
+	}
+	columnName, err := excelize.ColumnNumberToName(statusColumn)
+	if err != nil {
+		return err
+	}
+
+	formats := make([]excelize.ConditionalFormatOptions, 0, len(statusFills))
+	for _, fill := range statusFills {
+		style, err := e.file.NewConditionalStyle(&excelize.Style{
+			Font: &excelize.Font{Color: fill.font},
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{fill.fill}},
+		})
+		if err != nil {
+			return err
+		}
+		formats = append(formats, excelize.ConditionalFormatOptions{
+			Type:     "formula",
+			Criteria: fmt.Sprintf(`$%s2="%s"`, columnName, fill.status),
+			Format:   &style,
+		})
+	}
+	return e.file.SetConditionalFormat(SheetName, "A2:"+lastCell, formats)
+}
+
+func (e *XLSXExporter) Close() error {
+	return e.file.Close()
+}
@@ -0,0 +1,117 @@
+package orderexport
+
+import (
+	"bytes"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSXExporter(t *testing.T) {
+	text := func(s string) *string { return &s }
+	ordered := 8
+	orders := []models.PurchaseOrder{
+		{JobIDNo: text("J-001"), Customer: text("Acme"), Ordered: &ordered, PODate: utils.DateOrNil("2024-03-12"), Status: text("Completed")},
+		{JobIDNo: text("J-002"), Customer: text("Globex"), Status: text("Overdue")},
+	}
+
+	var buf bytes.Buffer
+	exporter, err := NewXLSXExporter(&buf, Columns())
+	assert.NoError(t, err)
+	defer exporter.Close()
+
+	for _, order := range orders {
+		assert.NoError(t, exporter.Write(order))
+	}
+	assert.NoError(t, exporter.Finish())
+
+	f, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{SheetName}, f.GetSheetList())
+
+	rows, err := f.GetRows(SheetName)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Job ID No", rows[0][0])
+	assert.Contains(t, rows[0], "PO Receive Date")
+	assert.Equal(t, "J-001", rows[1][0])
+
+	// Typed cells
+	orderedCell := cellOf(t, "ordered", 2)
+	cellType, err := f.GetCellType(SheetName, orderedCell)
+	assert.NoError(t, err)
+	assert.NotEqual(t, excelize.CellTypeSharedString, cellType)
+	raw, err := f.GetCellValue(SheetName, orderedCell, excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "8", raw)
+
+	poDateCell := cellOf(t, "po_date", 2)
+	formatted, err := f.GetCellValue(SheetName, poDateCell)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-12", formatted)
+	raw, err = f.GetCellValue(SheetName, poDateCell, excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "45363", raw)
+
+	// Frozen header
+	panes, err := f.GetPanes(SheetName)
+	assert.NoError(t, err)
+	assert.True(t, panes.Freeze)
+	assert.Equal(t, 1, panes.YSplit)
+
+	// Status colours over the data rows
+	formats, err := f.GetConditionalFormats(SheetName)
+	assert.NoError(t, err)
+	lastCell := cellOf(t, Columns()[len(Columns())-1].Field, 3)
+	assert.Len(t, formats["A2:"+lastCell], len(statusFills))
+	assert.Equal(t, `$`+columnLetter(t, "status")+`2="Completed"`, formats["A2:"+lastCell][0].Criteria)
+
+	// Autofilter over header and data
+	names := f.GetDefinedName()
+	assert.Len(t, names, 1)
+	assert.Equal(t, "_xlnm._FilterDatabase", names[0].Name)
+	assert.Contains(t, names[0].RefersTo, "$A$1:")
+}
+
+func TestNewXLSXExporter_NoColumns(t *testing.T) {
+	_, err := NewXLSXExporter(&bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "at least one column is required")
+}
+
+func TestColumns(t *testing.T) {
+	fields := []string{}
+	for _, column := range Columns() {
+		fields = append(fields, column.Field)
+	}
+
+	assert.Equal(t, "job_id_no", fields[0])
+	assert.Contains(t, fields, "delivery_date")
+	assert.Contains(t, fields, "status_reason")
+	assert.NotContains(t, fields, "warnings")
+	assert.NotContains(t, fields, "shipments")
+	assert.Equal(t, "Stock Picking Out Date", columnTitle("stock_picking_out_date"))
+}
+
+func columnLetter(t *testing.T, field string) string {
+	t.Helper()
+	for i, column := range Columns() {
+		if column.Field == field {
+			name, err := excelize.ColumnNumberToName(i + 1)
+			assert.NoError(t, err)
+			return name
+		}
+	}
+	t.Fatalf("unknown column %q", field)
+	return ""
+}
+
+func cellOf(t *testing.T, field string, row int) string {
+	t.Helper()
+	return columnLetter(t, field) + strconv.Itoa(row)
+}
@@ -0,0 +1,59 @@
+package orderfields
+
+import (
+	"purchase-record/internal/models"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Field is a text, quantity or date field of PurchaseOrder, named by its JSON
+// tag such as "po_receive_date". Exports write these fields and snapshot
+// diffs compare them.
+type Field struct {
+	Name  string
+	index int
+}
+
+// fields lists the fields in declaration order, read from the struct's JSON
+// tags. Shipments and warnings are derived from other fields and left out.
+var fields = func() []Field {
+	var result []Field
+	orderType := reflect.TypeOf(models.PurchaseOrder{})
+	for i := 0; i < orderType.NumField(); i++ {
+		field := orderType.Field(i)
+		if field.Type.Kind() != reflect.Pointer {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		result = append(result, Field{Name: name, index: i})
+	}
+	return result
+}()
+
+// Fields returns every field.
+func Fields() []Field {
+	return append([]Field(nil), fields...)
+}
+
+// Lookup returns the field with the given name.
+func Lookup(name string) (Field, bool) {
+	index := slices.IndexFunc(fields, func(f Field) bool { return f.Name == name })
+	if index < 0 {
+		return Field{}, false
+	}
+	return fields[index], true
+}
+
+// Value returns the field of order as a string, an int or a models.Date, or
+// nil when it is empty.
+func (f Field) Value(order models.PurchaseOrder) any {
+	value := reflect.ValueOf(order).Field(f.index)
+	if value.IsNil() {
+		return nil
+	}
+	return value.Elem().Interface()
+}
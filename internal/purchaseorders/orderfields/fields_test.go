@@ -0,0 +1,44 @@
+package orderfields
+
+import (
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields(t *testing.T) {
+	names := []string{}
+	for _, field := range Fields() {
+		names = append(names, field.Name)
+	}
+
+	assert.Equal(t, "job_id_no", names[0])
+	assert.Contains(t, names, "delivery_date")
+	assert.Contains(t, names, "status_reason")
+	assert.NotContains(t, names, "warnings")
+	assert.NotContains(t, names, "shipments")
+}
+
+func TestField_Value(t *testing.T) {
+	order := models.PurchaseOrder{
+		JobIDNo: utils.StringOrNil("J-001"),
+		Ordered: utils.IntPtrFromInt(8),
+		PODate:  utils.DateOrNil("2024-03-12"),
+	}
+
+	for name, expected := range map[string]any{
+		"job_id_no": "J-001",
+		"ordered":   8,
+		"po_date":   *utils.DateOrNil("2024-03-12"),
+		"customer":  nil,
+	} {
+		field, found := Lookup(name)
+		assert.True(t, found, name)
+		assert.Equal(t, expected, field.Value(order), name)
+	}
+
+	_, found := Lookup("price")
+	assert.False(t, found)
+}
@@ -0,0 +1,39 @@
+package orderquery
+
+import "purchase-record/internal/models"
+
+// Order fields by JSON name, grouped by type so filters and sort keys can
+// compare them without reflection.
+var textFields = map[string]func(models.PurchaseOrder) *string{
+	"job_id_no":           func(o models.PurchaseOrder) *string { return o.JobIDNo },
+	"type":                func(o models.PurchaseOrder) *string { return o.Type },
+	"sales_team":          func(o models.PurchaseOrder) *string { return o.SalesTeam },
+	"project_manager":     func(o models.PurchaseOrder) *string { return o.ProjectManager },
+	"purchasing":          func(o models.PurchaseOrder) *string { return o.Purchasing },
+	"customer":            func(o models.PurchaseOrder) *string { return o.Customer },
+	"product_code":        func(o models.PurchaseOrder) *string { return o.ProductCode },
+	"product_description": func(o models.PurchaseOrder) *string { return o.ProductDescription },
+	"pr":                  func(o models.PurchaseOrder) *string { return o.PR },
+	"po":                  func(o models.PurchaseOrder) *string { return o.PO },
+	"distribution":        func(o models.PurchaseOrder) *string { return o.Distribution },
+	"delivery_log":        func(o models.PurchaseOrder) *string { return o.DeliveryLog },
+	"status":              func(o models.PurchaseOrder) *string { return o.Status },
+	"status_reason":       func(o models.PurchaseOrder) *string { return o.StatusReason },
+	"remark":              func(o models.PurchaseOrder) *string { return o.Remark },
+}
+
+var intFields = map[string]func(models.PurchaseOrder) *int{
+	"ordered":  func(o models.PurchaseOrder) *int { return o.Ordered },
+	"received": func(o models.PurchaseOrder) *int { return o.Received },
+	"remain":   func(o models.PurchaseOrder) *int { return o.Remain },
+}
+
+var dateFields = map[string]func(models.PurchaseOrder) *models.Date{
+	"pr_date":                func(o models.PurchaseOrder) *models.Date { return o.PRDate },
+	"po_date":                func(o models.PurchaseOrder) *models.Date { return o.PODate },
+	"request_date":           func(o models.PurchaseOrder) *models.Date { return o.RequestDate },
+	"po_receive_date":        func(o models.PurchaseOrder) *models.Date { return o.POReceiveDate },
+	"received_date":          func(o models.PurchaseOrder) *models.Date { return o.ReceivedDate },
+	"stock_picking_out_date": func(o models.PurchaseOrder) *models.Date { return o.StockPickingOutDate },
+	"delivery_date":          func(o models.PurchaseOrder) *models.Date { return o.DeliveryDate },
+}
@@ -0,0 +1,176 @@
+package orderquery
+
+import (
+	"fmt"
+	"purchase-record/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rangeSeparator splits the lower and upper bound of a range
+const rangeSeparator = ".."
+
+// Filter matches orders against a parsed models.OrderFilter.
+type Filter struct {
+	text  []textCondition
+	ints  []intCondition
+	dates []dateCondition
+}
+
+// textCondition matches a text field against any of a set of lowercase values
+type textCondition struct {
+	value  func(models.PurchaseOrder) *string
+	values map[string]bool
+}
+
+// intCondition and dateCondition hold inclusive bounds; nil leaves a side open
+type intCondition struct {
+	value    func(models.PurchaseOrder) *int
+	min, max *int
+}
+
+type dateCondition struct {
+	value    func(models.PurchaseOrder) *models.Date
+	min, max *models.Date
+}
+
+// NewFilter parses the ranges of filter. An empty filter matches every order.
+func NewFilter(filter models.OrderFilter) (*Filter, error) {
+	f := &Filter{}
+
+	for field, values := range map[string][]string{
+		"sales_team":      filter.SalesTeam,
+		"project_manager": filter.ProjectManager,
+		"purchasing":      filter.Purchasing,
+		"customer":        filter.Customer,
+		"status":          filter.Status,
+		"type":            filter.Type,
+	} {
+		if condition, ok := newTextCondition(field, values); ok {
+			f.text = append(f.text, condition)
+		}
+	}
+
+	for _, r := range []struct{ param, field, value string }{
+		{"ordered", "ordered", filter.Ordered},
+		{"remain", "remain", filter.Remain},
+	} {
+		if r.value == "" {
+			continue
+		}
+		low, high, err := parseRange(r.value, parseInt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s range %q: %w", r.param, r.value, err)
+		}
+		f.ints = append(f.ints, intCondition{value: intFields[r.field], min: low, max: high})
+	}
+
+	for _, r := range []struct{ param, field, value string }{
+		{"prDate", "pr_date", filter.PRDate},
+		{"poDate", "po_date", filter.PODate},
+		{"requestDate", "request_date", filter.RequestDate},
+		{"poReceiveDate", "po_receive_date", filter.POReceiveDate},
+		{"receivedDate", "received_date", filter.ReceivedDate},
+		{"stockPickingOutDate", "stock_picking_out_date", filter.StockPickingOutDate},
+		{"deliveryDate", "delivery_date", filter.DeliveryDate},
+	} {
+		if r.value == "" {
+			continue
+		}
+		low, high, err := parseRange(r.value, parseDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s range %q: %w", r.param, r.value, err)
+		}
+		f.dates = append(f.dates, dateCondition{value: dateFields[r.field], min: low, max: high})
+	}
+
+	return f, nil
+}
+
+// newTextCondition ignores blank values and reports false when none remain
+func newTextCondition(field string, values []string) (textCondition, bool) {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			set[value] = true
+		}
+	}
+	return textCondition{value: textFields[field], values: set}, len(set) > 0
+}
+
+// Match reports whether the order satisfies every condition. An order with no
+// value for a filtered field never matches.
+func (f *Filter) Match(order models.PurchaseOrder) bool {
+	for _, c := range f.text {
+		value := c.value(order)
+		if value == nil || !c.values[strings.ToLower(strings.TrimSpace(*value))] {
+			return false
+		}
+	}
+	for _, c := range f.ints {
+		value := c.value(order)
+		if value == nil || (c.min != nil && *value < *c.min) || (c.max != nil && *value > *c.max) {
+			return false
+		}
+	}
+	for _, c := range f.dates {
+		value := c.value(order)
+		if value == nil || (c.min != nil && value.Before(c.min.Time)) || (c.max != nil && value.After(c.max.Time)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRange reads "min..max" with either side optional, or a single value
+// used as both bounds.
+func parseRange[T any](s string, parse func(string) (T, error)) (*T, *T, error) {
+	lower, upper, isRange := strings.Cut(s, rangeSeparator)
+	if !isRange {
+		upper = lower
+	}
+	lower, upper = strings.TrimSpace(lower), strings.TrimSpace(upper)
+	if lower == "" && upper == "" {
+		return nil, nil, fmt.Errorf("a range needs at least one bound")
+	}
+
+	bound := func(text string) (*T, error) {
+		if text == "" {
+			return nil, nil
+		}
+		value, err := parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return &value, nil
+	}
+
+	low, err := bound(lower)
+	if err != nil {
+		return nil, nil, err
+	}
+	high, err := bound(upper)
+	if err != nil {
+		return nil, nil, err
+	}
+	return low, high, nil
+}
+
+// parseDate reads a bound only as YYYY-MM-DD. The lenient parser used for
+// workbook cells would take a bare year such as 2024 for an Excel serial.
+func parseDate(s string) (models.Date, error) {
+	t, err := time.Parse(models.DateLayout, s)
+	if err != nil {
+		return models.Date{}, fmt.Errorf("'%s' is not a date in the form YYYY-MM-DD", s)
+	}
+	return models.NewDate(t), nil
+}
+
+func parseInt(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a whole number", s)
+	}
+	return n, nil
+}
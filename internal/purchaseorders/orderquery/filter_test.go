@@ -0,0 +1,183 @@
+package orderquery
+
+import (
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filterOrders() []models.PurchaseOrder {
+	order := func(jobID, customer, team string, ordered int, poDate string) models.PurchaseOrder {
+		return models.PurchaseOrder{
+			JobIDNo:   &jobID,
+			Customer:  &customer,
+			SalesTeam: &team,
+			Ordered:   &ordered,
+			PODate:    utils.DateOrNil(poDate),
+		}
+	}
+
+	noDate := order("J-005", "Acme Co", "Team B", 5, "")
+	return []models.PurchaseOrder{
+		order("J-001", "Globex", "Team A", 10, "2024-01-15"),
+		order("J-002", "Acme Co", "Team A", 50, "2024-02-20"),
+		order("J-003", "acme co", "Team B", 25, "2024-03-05"),
+		order("J-004", "Initech", "Team C", 100, "2024-02-01"),
+		noDate,
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      models.OrderFilter
+		expectedIDs []string
+	}{
+		{
+			name:        "empty filter matches everything",
+			expectedIDs: []string{"J-001", "J-002", "J-003", "J-004", "J-005"},
+		},
+		{
+			name:        "equality ignores case and accepts any listed value",
+			filter:      models.OrderFilter{Customer: []string{"ACME CO", "initech"}},
+			expectedIDs: []string{"J-002", "J-003", "J-004", "J-005"},
+		},
+		{
+			name:        "conditions on different fields all apply",
+			filter:      models.OrderFilter{Customer: []string{"acme co"}, SalesTeam: []string{"Team A"}},
+			expectedIDs: []string{"J-002"},
+		},
+		{
+			name:        "quantity range",
+			filter:      models.OrderFilter{Ordered: "10..50"},
+			expectedIDs: []string{"J-001", "J-002", "J-003"},
+		},
+		{
+			name:        "open quantity range",
+			filter:      models.OrderFilter{Ordered: "..10"},
+			expectedIDs: []string{"J-001", "J-005"},
+		},
+		{
+			name:        "exact quantity",
+			filter:      models.OrderFilter{Ordered: "25"},
+			expectedIDs: []string{"J-003"},
+		},
+		{
+			name:        "date range excludes orders without the date",
+			filter:      models.OrderFilter{PODate: "2024-02-01.."},
+			expectedIDs: []string{"J-002", "J-003", "J-004"},
+		},
+		{
+			name:        "closed date range",
+			filter:      models.OrderFilter{PODate: "2024-02-01..2024-02-29"},
+			expectedIDs: []string{"J-002", "J-004"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.filter)
+			assert.NoError(t, err)
+
+			ids := []string{}
+			for _, order := range filterOrders() {
+				if filter.Match(order) {
+					ids = append(ids, *order.JobIDNo)
+				}
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestNewFilter_InvalidRange(t *testing.T) {
+	_, err := NewFilter(models.OrderFilter{Ordered: "ten.."})
+	assert.EqualError(t, err, `invalid ordered range "ten..": 'ten' is not a whole number`)
+
+	_, err = NewFilter(models.OrderFilter{PODate: ".."})
+	assert.EqualError(t, err, `invalid poDate range "..": a range needs at least one bound`)
+
+	_, err = NewFilter(models.OrderFilter{RequestDate: "2024-01-01..soon"})
+	assert.ErrorContains(t, err, `invalid requestDate range "2024-01-01..soon"`)
+
+	// Bounds are ISO dates only, so a bare year is not taken for an Excel serial
+	_, err = NewFilter(models.OrderFilter{PRDate: "2024..2025"})
+	assert.EqualError(t, err, `invalid prDate range "2024..2025": '2024' is not a date in the form YYYY-MM-DD`)
+
+	_, err = NewFilter(models.OrderFilter{PODate: "01/02/2024.."})
+	assert.ErrorContains(t, err, `'01/02/2024' is not a date in the form YYYY-MM-DD`)
+}
+
+func TestSorter(t *testing.T) {
+	tests := []struct {
+		name        string
+		sort        string
+		expectedIDs []string
+	}{
+		{
+			name:        "text ignores case and keeps ties in order",
+			sort:        "customer",
+			expectedIDs: []string{"J-002", "J-003", "J-005", "J-001", "J-004"},
+		},
+		{
+			name:        "descending number",
+			sort:        "-ordered",
+			expectedIDs: []string{"J-004", "J-002", "J-003", "J-001", "J-005"},
+		},
+		{
+			name:        "missing dates go last in either direction",
+			sort:        "-po_date",
+			expectedIDs: []string{"J-003", "J-002", "J-004", "J-001", "J-005"},
+		},
+		{
+			name:        "multiple keys",
+			sort:        "sales_team, -ordered",
+			expectedIDs: []string{"J-002", "J-001", "J-003", "J-005", "J-004"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter, err := NewSorter(tt.sort)
+			assert.NoError(t, err)
+
+			orders := filterOrders()
+			sorter.Sort(orders)
+			assert.Equal(t, tt.expectedIDs, jobIDs(orders))
+		})
+	}
+}
+
+func TestNewSorter(t *testing.T) {
+	sorter, err := NewSorter(" , ")
+	assert.NoError(t, err)
+	assert.Nil(t, sorter)
+
+	_, err = NewSorter("customer,-price")
+	assert.EqualError(t, err, `cannot sort by unknown field "price"`)
+}
+
+func TestApply_SortedPage(t *testing.T) {
+	page, err := Apply(filterOrders(), models.RequestQuery{
+		OrderFilter: models.OrderFilter{Ordered: "10..", Sort: "-ordered"},
+		PageNo:      2,
+		PageSize:    2,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"J-003", "J-001"}, jobIDs(page.Orders))
+	assert.Equal(t, int64(4), page.Total)
+	assert.Equal(t, int64(2), page.TotalPages)
+
+	page, err = Apply(filterOrders(), models.RequestQuery{
+		OrderFilter: models.OrderFilter{Sort: "job_id_no"},
+		PageNo:      4,
+		PageSize:    2,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, page.Orders)
+	assert.NotNil(t, page.Orders)
+}
@@ -0,0 +1,201 @@
+package orderquery
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"strings"
+)
+
+// Paginator selects the orders matching a RequestQuery and keeps only those
+// that fall on the requested page, so orders can be fed to it one at a time.
+// A sorted query cannot know the page until every order is seen, so its
+// matches are held by the paginator until Page is called.
+type Paginator struct {
+	terms    []string
+	filter   *Filter
+	sorter   *Sorter
+	pageNo   int64
+	pageSize int64
+	total    int64
+	matches  []models.PurchaseOrder
+}
+
+// NewPaginator validates the paging parameters. PageNo defaults to 1 and a
+// PageSize of zero puts every matching order on a single page.
+func NewPaginator(query models.RequestQuery) (*Paginator, error) {
+	if err := ValidateQuery(query); err != nil {
+		return nil, err
+	}
+	filter, err := NewFilter(query.OrderFilter)
+	if err != nil {
+		return nil, err
+	}
+	sorter, err := NewSorter(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	pageNo := query.PageNo
+	if pageNo == 0 {
+		pageNo = 1
+	}
+
+	return &Paginator{
+		terms:    SearchTerms(query.Search),
+		filter:   filter,
+		sorter:   sorter,
+		pageNo:   pageNo,
+		pageSize: query.PageSize,
+	}, nil
+}
+
+// ValidateQuery rejects paging parameters that cannot select a page as well
+// as filters and sort keys that cannot be parsed.
+func ValidateQuery(query models.RequestQuery) error {
+	if query.PageNo < 0 {
+		return fmt.Errorf("pageNo must not be negative")
+	}
+	if query.PageSize < 0 {
+		return fmt.Errorf("pageSize must not be negative")
+	}
+	// The offset of the page must fit in an int64
+	if query.PageSize > 0 && query.PageNo > 1 && query.PageNo-1 > math.MaxInt64/query.PageSize {
+		return fmt.Errorf("pageNo %d is out of range for pageSize %d", query.PageNo, query.PageSize)
+	}
+	if _, err := NewFilter(query.OrderFilter); err != nil {
+		return err
+	}
+	if _, err := NewSorter(query.Sort); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Sorted reports whether the page is only known once every order was added.
+func (p *Paginator) Sorted() bool {
+	return p.sorter != nil
+}
+
+// Add counts the order if it matches the search and filters and reports
+// whether it belongs on the requested page. For sorted queries it keeps the
+// order itself and always reports false.
+func (p *Paginator) Add(order models.PurchaseOrder) bool {
+	if !MatchesSearch(order, p.terms) || !p.filter.Match(order) {
+		return false
+	}
+	p.total++
+
+	if p.sorter != nil {
+		p.matches = append(p.matches, order)
+		return false
+	}
+
+	if p.pageSize == 0 {
+		return true
+	}
+	// Worked out from the order's position so no offset can overflow
+	return (p.total-1)/p.pageSize == p.pageNo-1
+}
+
+// Page wraps the orders collected from Add with the page metadata. Sorted
+// queries ignore orders and return the requested slice of the sorted matches.
+func (p *Paginator) Page(orders []models.PurchaseOrder) models.OrderPage {
+	if p.sorter != nil {
+		orders = p.sortedPage()
+	}
+
+	totalPages := int64(1)
+	pageSize := p.pageSize
+	if pageSize == 0 {
+		pageSize = p.total
+	} else {
+		totalPages = p.total / pageSize
+		if p.total%pageSize != 0 {
+			totalPages++
+		}
+	}
+	if p.total == 0 {
+		totalPages = 0
+	}
+
+	if orders == nil {
+		orders = []models.PurchaseOrder{}
+	}
+
+	return models.OrderPage{
+		Orders:     orders,
+		Total:      p.total,
+		PageNo:     p.pageNo,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}
+}
+
+// sortedPage sorts the held matches and returns those on the requested page
+func (p *Paginator) sortedPage() []models.PurchaseOrder {
+	p.sorter.Sort(p.matches)
+	if p.pageSize == 0 {
+		return p.matches
+	}
+	count := int64(len(p.matches))
+	if count == 0 || p.pageNo-1 > (count-1)/p.pageSize {
+		return nil
+	}
+	first := (p.pageNo - 1) * p.pageSize
+	return p.matches[first : first+min(p.pageSize, count-first)]
+}
+
+// Apply filters, sorts and pages a slice of orders in one call, for callers
+// that already hold every order.
+func Apply(orders []models.PurchaseOrder, query models.RequestQuery) (models.OrderPage, error) {
+	paginator, err := NewPaginator(query)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	var selected []models.PurchaseOrder
+	for _, order := range orders {
+		if paginator.Add(order) {
+			selected = append(selected, order)
+		}
+	}
+	return paginator.Page(selected), nil
+}
+
+// SearchTerms splits a search into the lowercase terms MatchesSearch expects.
+func SearchTerms(search string) []string {
+	return strings.Fields(strings.ToLower(search))
+}
+
+// MatchesSearch reports whether every term appears, case-insensitively, in at
+// least one of the order's job ID, customer, product code, product
+// description, PR or PO. Terms must already be lowercase.
+func MatchesSearch(order models.PurchaseOrder, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+
+	fields := []*string{
+		order.JobIDNo,
+		order.Customer,
+		order.ProductCode,
+		order.ProductDescription,
+		order.PR,
+		order.PO,
+	}
+
+	for _, term := range terms {
+		found := false
+		for _, field := range fields {
+			if field != nil && strings.Contains(strings.ToLower(*field), term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
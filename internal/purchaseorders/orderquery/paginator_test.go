@@ -0,0 +1,147 @@
+package orderquery
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOrders() []models.PurchaseOrder {
+	orders := make([]models.PurchaseOrder, 0, 25)
+	for i := 1; i <= 25; i++ {
+		jobID := fmt.Sprintf("J-%03d", i)
+		customer := "Acme Co"
+		if i%5 == 0 {
+			customer = "Globex"
+		}
+		orders = append(orders, models.PurchaseOrder{JobIDNo: &jobID, Customer: &customer})
+	}
+	return orders
+}
+
+func jobIDs(orders []models.PurchaseOrder) []string {
+	ids := []string{}
+	for _, order := range orders {
+		ids = append(ids, *order.JobIDNo)
+	}
+	return ids
+}
+
+func TestPaginator(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              models.RequestQuery
+		expectedIDs        []string
+		expectedTotal      int64
+		expectedPageNo     int64
+		expectedPageSize   int64
+		expectedTotalPages int64
+	}{
+		{
+			name:               "no paging returns every order",
+			query:              models.RequestQuery{Search: "globex"},
+			expectedIDs:        []string{"J-005", "J-010", "J-015", "J-020", "J-025"},
+			expectedTotal:      5,
+			expectedPageNo:     1,
+			expectedPageSize:   5,
+			expectedTotalPages: 1,
+		},
+		{
+			name:               "second page",
+			query:              models.RequestQuery{PageNo: 2, PageSize: 10},
+			expectedIDs:        []string{"J-011", "J-012", "J-013", "J-014", "J-015", "J-016", "J-017", "J-018", "J-019", "J-020"},
+			expectedTotal:      25,
+			expectedPageNo:     2,
+			expectedPageSize:   10,
+			expectedTotalPages: 3,
+		},
+		{
+			name:               "last partial page of a search",
+			query:              models.RequestQuery{Search: "ACME j-02", PageNo: 2, PageSize: 3},
+			expectedIDs:        []string{"J-024"},
+			expectedTotal:      4,
+			expectedPageNo:     2,
+			expectedPageSize:   3,
+			expectedTotalPages: 2,
+		},
+		{
+			name:               "page past the end",
+			query:              models.RequestQuery{PageNo: 9, PageSize: 10},
+			expectedIDs:        []string{},
+			expectedTotal:      25,
+			expectedPageNo:     9,
+			expectedPageSize:   10,
+			expectedTotalPages: 3,
+		},
+		{
+			name:               "no match",
+			query:              models.RequestQuery{Search: "initech"},
+			expectedIDs:        []string{},
+			expectedTotal:      0,
+			expectedPageNo:     1,
+			expectedPageSize:   0,
+			expectedTotalPages: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paginator, err := NewPaginator(tt.query)
+			assert.NoError(t, err)
+
+			var selected []models.PurchaseOrder
+			for _, order := range testOrders() {
+				if paginator.Add(order) {
+					selected = append(selected, order)
+				}
+			}
+			page := paginator.Page(selected)
+
+			assert.Equal(t, tt.expectedIDs, jobIDs(page.Orders))
+			assert.Equal(t, tt.expectedTotal, page.Total)
+			assert.Equal(t, tt.expectedPageNo, page.PageNo)
+			assert.Equal(t, tt.expectedPageSize, page.PageSize)
+			assert.Equal(t, tt.expectedTotalPages, page.TotalPages)
+		})
+	}
+}
+
+func TestNewPaginator_InvalidQuery(t *testing.T) {
+	_, err := NewPaginator(models.RequestQuery{PageNo: -1})
+	assert.EqualError(t, err, "pageNo must not be negative")
+
+	_, err = NewPaginator(models.RequestQuery{PageSize: -1})
+	assert.EqualError(t, err, "pageSize must not be negative")
+
+	_, err = NewPaginator(models.RequestQuery{PageNo: 1<<61 + 1, PageSize: 5, OrderFilter: models.OrderFilter{Sort: "customer"}})
+	assert.EqualError(t, err, "pageNo 2305843009213693953 is out of range for pageSize 5")
+}
+
+func TestPaginator_LargePages(t *testing.T) {
+	// The last page whose offset fits is empty, sorted or not, without overflowing
+	for _, sort := range []string{"", "customer"} {
+		query := models.RequestQuery{PageNo: math.MaxInt64/5 + 1, PageSize: 5, OrderFilter: models.OrderFilter{Sort: sort}}
+		page, err := Apply(testOrders(), query)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Orders)
+		assert.Equal(t, int64(5), page.TotalPages)
+	}
+
+	page, err := Apply(testOrders(), models.RequestQuery{PageSize: math.MaxInt64, OrderFilter: models.OrderFilter{Sort: "customer"}})
+	assert.NoError(t, err)
+	assert.Len(t, page.Orders, 25)
+	assert.Equal(t, int64(1), page.TotalPages)
+}
+
+func TestMatchesSearch(t *testing.T) {
+	description := "Cisco Switch 24 Port"
+	po := "PO-7788"
+	order := models.PurchaseOrder{ProductDescription: &description, PO: &po}
+
+	assert.True(t, MatchesSearch(order, nil))
+	assert.True(t, MatchesSearch(order, []string{"switch", "7788"}))
+	assert.False(t, MatchesSearch(order, []string{"switch", "router"}))
+}
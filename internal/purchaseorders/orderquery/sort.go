@@ -0,0 +1,91 @@
+package orderquery
+
+import (
+	"cmp"
+	"fmt"
+	"purchase-record/internal/models"
+	"slices"
+	"strings"
+)
+
+// Sorter orders purchase orders by one or more fields.
+type Sorter struct {
+	keys []sortKey
+}
+
+type sortKey struct {
+	compare    func(a, b models.PurchaseOrder) (int, bool)
+	descending bool
+}
+
+// NewSorter parses a comma-separated list of JSON field names, each optionally
+// prefixed with "-" for descending order. It returns nil for an empty list.
+func NewSorter(sort string) (*Sorter, error) {
+	var keys []sortKey
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		key := sortKey{}
+		if strings.HasPrefix(name, "-") {
+			key.descending = true
+			name = name[1:]
+		} else {
+			name = strings.TrimPrefix(name, "+")
+		}
+
+		if text := textFields[name]; text != nil {
+			key.compare = compareBy(text, func(a, b string) int {
+				return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+			})
+		} else if number := intFields[name]; number != nil {
+			key.compare = compareBy(number, cmp.Compare[int])
+		} else if date := dateFields[name]; date != nil {
+			key.compare = compareBy(date, func(a, b models.Date) int { return a.Compare(b.Time) })
+		} else {
+			return nil, fmt.Errorf("cannot sort by unknown field %q", name)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &Sorter{keys: keys}, nil
+}
+
+// compareBy compares a field of two orders. The boolean is false when either
+// value is missing, in which case the result only places the missing one last.
+func compareBy[T any](value func(models.PurchaseOrder) *T, compare func(a, b T) int) func(a, b models.PurchaseOrder) (int, bool) {
+	return func(a, b models.PurchaseOrder) (int, bool) {
+		va, vb := value(a), value(b)
+		switch {
+		case va == nil && vb == nil:
+			return 0, false
+		case va == nil:
+			return 1, false
+		case vb == nil:
+			return -1, false
+		}
+		return compare(*va, *vb), true
+	}
+}
+
+// Sort orders the slice in place. Ties keep their original order and missing
+// values go last whatever the direction.
+func (s *Sorter) Sort(orders []models.PurchaseOrder) {
+	slices.SortStableFunc(orders, func(a, b models.PurchaseOrder) int {
+		for _, key := range s.keys {
+			result, present := key.compare(a, b)
+			if present && key.descending {
+				result = -result
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	})
+}
@@ -0,0 +1,98 @@
+package orderquery
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SummaryGroupFields are the JSON field names orders can be grouped by.
+var SummaryGroupFields = []string{"customer", "sales_team", "project_manager", "purchasing", "status"}
+
+// Summarizer accumulates per-group totals as orders are added one at a time.
+type Summarizer struct {
+	groupBy string
+	key     func(models.PurchaseOrder) *string
+	groups  map[string]*models.SummaryGroup
+	total   models.SummaryGroup
+}
+
+// NewSummarizer groups by one of SummaryGroupFields.
+func NewSummarizer(groupBy string) (*Summarizer, error) {
+	if !slices.Contains(SummaryGroupFields, groupBy) {
+		return nil, fmt.Errorf("groupBy must be one of %s", strings.Join(SummaryGroupFields, ", "))
+	}
+	return &Summarizer{
+		groupBy: groupBy,
+		key:     textFields[groupBy],
+		groups:  map[string]*models.SummaryGroup{},
+	}, nil
+}
+
+// Add counts the order towards its group and the overall total. Values that
+// differ only in case share a group named after the first spelling seen.
+func (s *Summarizer) Add(order models.PurchaseOrder) {
+	key := ""
+	if value := s.key(order); value != nil {
+		key = strings.TrimSpace(*value)
+	}
+
+	group := s.groups[strings.ToLower(key)]
+	if group == nil {
+		group = &models.SummaryGroup{Key: key}
+		s.groups[strings.ToLower(key)] = group
+	}
+	addToGroup(group, order)
+	addToGroup(&s.total, order)
+}
+
+func addToGroup(group *models.SummaryGroup, order models.PurchaseOrder) {
+	ordered, received := valueOrZero(order.Ordered), valueOrZero(order.Received)
+
+	group.Lines++
+	group.Ordered += int64(ordered)
+	group.Received += int64(received)
+	group.Remain += int64(valueOrZero(order.Remain))
+	if order.Ordered != nil && received >= ordered {
+		group.CompletedLines++
+	}
+}
+
+// Summary returns the groups sorted by key with their completion percentages.
+func (s *Summarizer) Summary() models.OrderSummary {
+	keys := make([]string, 0, len(s.groups))
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	groups := make([]models.SummaryGroup, 0, len(keys))
+	for _, key := range keys {
+		group := *s.groups[key]
+		group.CompletionPercent = completionPercent(group)
+		groups = append(groups, group)
+	}
+
+	total := s.total
+	total.CompletionPercent = completionPercent(total)
+
+	return models.OrderSummary{GroupBy: s.groupBy, Groups: groups, Total: total}
+}
+
+// completionPercent is rounded to two decimals and is zero when nothing was ordered
+func completionPercent(group models.SummaryGroup) float64 {
+	if group.Ordered == 0 {
+		return 0
+	}
+	return math.Round(float64(group.Received)/float64(group.Ordered)*10000) / 100
+}
+
+func valueOrZero(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
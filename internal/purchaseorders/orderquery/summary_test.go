@@ -0,0 +1,41 @@
+package orderquery
+
+import (
+	"purchase-record/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizer(t *testing.T) {
+	summarizer, err := NewSummarizer("customer")
+	assert.NoError(t, err)
+
+	orders := filterOrders()
+	received := []int{10, 20, 25, 0, 5}
+	for i := range orders {
+		orders[i].Received = &received[i]
+		remain := *orders[i].Ordered - received[i]
+		orders[i].Remain = &remain
+		summarizer.Add(orders[i])
+	}
+	summarizer.Add(models.PurchaseOrder{})
+
+	summary := summarizer.Summary()
+
+	assert.Equal(t, "customer", summary.GroupBy)
+	assert.Equal(t, []models.SummaryGroup{
+		{Key: "", Lines: 1},
+		{Key: "Acme Co", Lines: 3, CompletedLines: 2, Ordered: 80, Received: 50, Remain: 30, CompletionPercent: 62.5},
+		{Key: "Globex", Lines: 1, CompletedLines: 1, Ordered: 10, Received: 10, Remain: 0, CompletionPercent: 100},
+		{Key: "Initech", Lines: 1, Ordered: 100, Remain: 100},
+	}, summary.Groups)
+	assert.Equal(t, models.SummaryGroup{
+		Lines: 6, CompletedLines: 3, Ordered: 190, Received: 60, Remain: 130, CompletionPercent: 31.58,
+	}, summary.Total)
+}
+
+func TestNewSummarizer_UnknownField(t *testing.T) {
+	_, err := NewSummarizer("product_code")
+	assert.EqualError(t, err, "groupBy must be one of customer, sales_team, project_manager, purchasing, status")
+}
@@ -0,0 +1,39 @@
+package orderstatus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCancelKeywords mark an order as cancelled in English or Thai.
+var DefaultCancelKeywords = []string{"cancel", "ยกเลิก"}
+
+// CancelledRule marks an order cancelled when its remark or its status cell
+// contains one of the keywords, ignoring case.
+type CancelledRule struct {
+	Keywords []string
+}
+
+func (r *CancelledRule) Evaluate(f Facts) (Result, bool) {
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"remark", f.Order.Remark},
+		{"status", f.Order.Status},
+	} {
+		if field.value == nil {
+			continue
+		}
+		text := strings.ToLower(*field.value)
+		for _, keyword := range r.Keywords {
+			if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+				return Result{
+					Status: Cancelled,
+					Reason: fmt.Sprintf("%s mentions %q", field.name, keyword),
+				}, true
+			}
+		}
+	}
+	return Result{}, false
+}
@@ -0,0 +1,150 @@
+package orderstatus
+
+import (
+	"fmt"
+	"purchase-record/internal/models"
+	"time"
+)
+
+// Delivery statuses produced by the default rules
+const (
+	NotStarted         = "Not Started"
+	PartiallyDelivered = "Partially Delivered"
+	Completed          = "Completed"
+	OverDelivered      = "Over-delivered"
+	Overdue            = "Overdue"
+	Cancelled          = "Cancelled"
+)
+
+// Facts is what the rules know about an order line when deciding its status.
+type Facts struct {
+	Order models.PurchaseOrder
+	// Ordered is the ordered quantity, nil when the cell is empty
+	Ordered *int
+	// Delivered is the number of units recorded in the delivery log
+	Delivered int
+	// Today is the date overdue orders are measured against
+	Today models.Date
+}
+
+// Result is a status and the reason it was chosen.
+type Result struct {
+	Status string
+	Reason string
+}
+
+// IStatusRule decides the status of an order, or reports false to leave the
+// decision to the next rule.
+type IStatusRule interface {
+	Evaluate(facts Facts) (Result, bool)
+}
+
+// StatusRuleFunc adapts a function to IStatusRule.
+type StatusRuleFunc func(facts Facts) (Result, bool)
+
+func (f StatusRuleFunc) Evaluate(facts Facts) (Result, bool) {
+	return f(facts)
+}
+
+type IStatusEngine interface {
+	Evaluate(facts Facts) Result
+}
+
+// StatusEngine asks its rules in order and uses the first decision.
+type StatusEngine struct {
+	Rules []IStatusRule
+	// Now returns the current time; it defaults to time.Now
+	Now func() time.Time
+}
+
+// NewStatusEngine builds an engine from rules, or from DefaultRules when none
+// are given.
+func NewStatusEngine(rules ...IStatusRule) IStatusEngine {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return &StatusEngine{Rules: rules, Now: time.Now}
+}
+
+// Evaluate fills in Today when it is unset and returns the first rule's
+// decision. An order no rule decides on is Not Started.
+func (e *StatusEngine) Evaluate(facts Facts) Result {
+	if facts.Today.IsZero() {
+		now := time.Now
+		if e.Now != nil {
+			now = e.Now
+		}
+		facts.Today = models.NewDate(now())
+	}
+
+	for _, rule := range e.Rules {
+		if result, ok := rule.Evaluate(facts); ok {
+			return result
+		}
+	}
+	return Result{Status: NotStarted, Reason: "no delivery recorded"}
+}
+
+// DefaultRules returns the built-in rules from most to least specific:
+// cancelled, over-delivered, completed, overdue, partially delivered and not
+// started.
+func DefaultRules() []IStatusRule {
+	return []IStatusRule{
+		&CancelledRule{Keywords: DefaultCancelKeywords},
+		StatusRuleFunc(overDelivered),
+		StatusRuleFunc(completed),
+		StatusRuleFunc(overdue),
+		StatusRuleFunc(partiallyDelivered),
+		StatusRuleFunc(notStarted),
+	}
+}
+
+func overDelivered(f Facts) (Result, bool) {
+	if f.Ordered == nil || f.Delivered <= *f.Ordered {
+		return Result{}, false
+	}
+	return Result{
+		Status: OverDelivered,
+		Reason: fmt.Sprintf("delivered %d of %d units, %d more than ordered", f.Delivered, *f.Ordered, f.Delivered-*f.Ordered),
+	}, true
+}
+
+func completed(f Facts) (Result, bool) {
+	if f.Ordered == nil || *f.Ordered == 0 || f.Delivered != *f.Ordered {
+		return Result{}, false
+	}
+	return Result{Status: Completed, Reason: fmt.Sprintf("delivered all %d units", *f.Ordered)}, true
+}
+
+func overdue(f Facts) (Result, bool) {
+	requestDate := f.Order.RequestDate
+	if requestDate == nil || !requestDate.Before(f.Today.Time) {
+		return Result{}, false
+	}
+	return Result{
+		Status: Overdue,
+		Reason: fmt.Sprintf("request date %s has passed with %s", requestDate, deliveredText(f)),
+	}, true
+}
+
+func partiallyDelivered(f Facts) (Result, bool) {
+	if f.Delivered == 0 {
+		return Result{}, false
+	}
+	return Result{Status: PartiallyDelivered, Reason: deliveredText(f)}, true
+}
+
+func notStarted(f Facts) (Result, bool) {
+	if f.Ordered == nil {
+		return Result{Status: NotStarted, Reason: "ordered quantity is missing"}, true
+	}
+	return Result{Status: NotStarted, Reason: deliveredText(f)}, true
+}
+
+// deliveredText describes the delivered share, such as "delivered 3 of 8 units"
+func deliveredText(f Facts) string {
+	if f.Ordered == nil {
+		return fmt.Sprintf("delivered %d units", f.Delivered)
+	}
+	return fmt.Sprintf("delivered %d of %d units", f.Delivered, *f.Ordered)
+}
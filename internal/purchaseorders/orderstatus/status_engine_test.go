@@ -0,0 +1,116 @@
+package orderstatus
+
+import (
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusEngine_DefaultRules(t *testing.T) {
+	ordered := 10
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name           string
+		facts          Facts
+		expectedStatus string
+		expectedReason string
+	}{
+		{
+			name:           "nothing delivered",
+			facts:          Facts{Ordered: &ordered},
+			expectedStatus: NotStarted,
+			expectedReason: "delivered 0 of 10 units",
+		},
+		{
+			name:           "missing ordered quantity",
+			facts:          Facts{},
+			expectedStatus: NotStarted,
+			expectedReason: "ordered quantity is missing",
+		},
+		{
+			name:           "part delivered",
+			facts:          Facts{Ordered: &ordered, Delivered: 4},
+			expectedStatus: PartiallyDelivered,
+			expectedReason: "delivered 4 of 10 units",
+		},
+		{
+			name:           "everything delivered",
+			facts:          Facts{Ordered: &ordered, Delivered: 10},
+			expectedStatus: Completed,
+			expectedReason: "delivered all 10 units",
+		},
+		{
+			name:           "more delivered than ordered",
+			facts:          Facts{Ordered: &ordered, Delivered: 12},
+			expectedStatus: OverDelivered,
+			expectedReason: "delivered 12 of 10 units, 2 more than ordered",
+		},
+		{
+			name:           "request date passed",
+			facts:          Facts{Order: models.PurchaseOrder{RequestDate: utils.DateOrNil("2024-03-01")}, Ordered: &ordered, Delivered: 4},
+			expectedStatus: Overdue,
+			expectedReason: "request date 2024-03-01 has passed with delivered 4 of 10 units",
+		},
+		{
+			name:           "request date today is not overdue",
+			facts:          Facts{Order: models.PurchaseOrder{RequestDate: utils.DateOrNil("2024-03-15")}, Ordered: &ordered},
+			expectedStatus: NotStarted,
+			expectedReason: "delivered 0 of 10 units",
+		},
+		{
+			name:           "completed orders are never overdue",
+			facts:          Facts{Order: models.PurchaseOrder{RequestDate: utils.DateOrNil("2024-03-01")}, Ordered: &ordered, Delivered: 10},
+			expectedStatus: Completed,
+			expectedReason: "delivered all 10 units",
+		},
+		{
+			name:           "cancelled in the remark",
+			facts:          Facts{Order: models.PurchaseOrder{Remark: text("Customer CANCELLED order")}, Ordered: &ordered, Delivered: 12},
+			expectedStatus: Cancelled,
+			expectedReason: `remark mentions "cancel"`,
+		},
+		{
+			name:           "cancelled in Thai",
+			facts:          Facts{Order: models.PurchaseOrder{Status: text("ยกเลิก")}, Ordered: &ordered},
+			expectedStatus: Cancelled,
+			expectedReason: `status mentions "ยกเลิก"`,
+		},
+	}
+
+	engine := &StatusEngine{
+		Rules: DefaultRules(),
+		Now:   func() time.Time { return time.Date(2024, 3, 15, 18, 0, 0, 0, time.Local) },
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := engine.Evaluate(tt.facts)
+
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.expectedReason, result.Reason)
+		})
+	}
+}
+
+func TestStatusEngine_CustomRules(t *testing.T) {
+	onHold := StatusRuleFunc(func(f Facts) (Result, bool) {
+		if f.Order.Remark != nil && *f.Order.Remark == "hold" {
+			return Result{Status: "On Hold", Reason: "held by purchasing"}, true
+		}
+		return Result{}, false
+	})
+	engine := NewStatusEngine(onHold, &CancelledRule{Keywords: []string{"void"}})
+
+	remark := "hold"
+	assert.Equal(t, Result{Status: "On Hold", Reason: "held by purchasing"}, engine.Evaluate(Facts{Order: models.PurchaseOrder{Remark: &remark}}))
+
+	remark = "void"
+	assert.Equal(t, Result{Status: Cancelled, Reason: `remark mentions "void"`}, engine.Evaluate(Facts{Order: models.PurchaseOrder{Remark: &remark}}))
+
+	// Orders no rule decides on fall back to Not Started
+	assert.Equal(t, Result{Status: NotStarted, Reason: "no delivery recorded"}, engine.Evaluate(Facts{}))
+}
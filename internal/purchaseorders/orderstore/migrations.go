@@ -0,0 +1,75 @@
+package orderstore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migrations are the schema changes of the store, applied in order. Version
+// n is migrations[n-1]; add new steps at the end instead of editing old ones,
+// since existing databases only run the steps they have not seen.
+var migrations = []string{
+	// 1: imports and the orders read by each of them
+	`CREATE TABLE imports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_path TEXT NOT NULL,
+		imported_at TEXT NOT NULL,
+		row_count INTEGER NOT NULL
+	);
+	CREATE INDEX imports_source_path ON imports (source_path, id);
+	CREATE TABLE orders (
+		import_id INTEGER NOT NULL REFERENCES imports (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		job_id_no TEXT,
+		data TEXT NOT NULL,
+		PRIMARY KEY (import_id, position)
+	);`,
+	// 2: content hash of the imported workbook
+	`ALTER TABLE imports ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';`,
+}
+
+// migrate brings the schema up to the latest version, recording each applied
+// step in schema_migrations. It refuses databases written by a newer build.
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		if err := applyMigration(db, version+1, migrations[version]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, version int, statements string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(statements); err != nil {
+		return fmt.Errorf("failed to apply migration %d: %w", version, err)
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
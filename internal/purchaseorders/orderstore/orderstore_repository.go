@@ -0,0 +1,207 @@
+package orderstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"purchase-record/internal/models"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// IOrderStoreRepository keeps the orders of each import so they can be served
+// when the workbook they came from cannot be read.
+type IOrderStoreRepository interface {
+	SaveImport(sourcePath string, contentHash string, orders []models.PurchaseOrder) (models.StoredImport, error)
+	LatestImport(sourcePath string) (models.StoredImport, bool, error)
+	GetImport(importID int64) (models.StoredImport, bool, error)
+	ListImports(sourcePath string) ([]models.StoredImport, error)
+	GetImportOrders(importID int64) ([]models.PurchaseOrder, error)
+	Close() error
+}
+
+// importColumns are read by scanImport
+const importColumns = `id, source_path, imported_at, row_count, content_hash`
+
+type OrderStoreRepository struct {
+	db  *sql.DB
+	Now func() time.Time
+}
+
+// NewOrderStoreRepository opens or creates the SQLite database at dbPath and
+// migrates it to the current schema.
+func NewOrderStoreRepository(dbPath string) (IOrderStoreRepository, error) {
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open order store '%s': %w", dbPath, err)
+	}
+	// SQLite allows one writer at a time
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate order store '%s': %w", dbPath, err)
+	}
+	return &OrderStoreRepository{db: db, Now: time.Now}, nil
+}
+
+// SaveImport records the orders read from sourcePath as a new import.
+// Orders are kept as JSON in the order they were read. When contentHash is
+// that of the source's latest import the workbook has not changed, so nothing
+// is saved and the latest import is returned.
+func (r *OrderStoreRepository) SaveImport(sourcePath string, contentHash string, orders []models.PurchaseOrder) (models.StoredImport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.StoredImport{}, err
+	}
+	defer tx.Rollback()
+
+	// Checked within the transaction so concurrent imports of the same
+	// workbook cannot both find it changed
+	if contentHash != "" {
+		latest, found, err := latestImport(tx, sourcePath)
+		if err != nil {
+			return models.StoredImport{}, err
+		}
+		if found && latest.ContentHash == contentHash {
+			return latest, nil
+		}
+	}
+
+	stored := models.StoredImport{
+		SourcePath:  sourcePath,
+		ImportedAt:  r.Now().UTC(),
+		RowCount:    len(orders),
+		ContentHash: contentHash,
+	}
+
+	result, err := tx.Exec(`INSERT INTO imports (source_path, imported_at, row_count, content_hash) VALUES (?, ?, ?, ?)`,
+		stored.SourcePath, stored.ImportedAt.Format(time.RFC3339Nano), stored.RowCount, stored.ContentHash)
+	if err != nil {
+		return models.StoredImport{}, fmt.Errorf("failed to save import of '%s': %w", sourcePath, err)
+	}
+	if stored.ID, err = result.LastInsertId(); err != nil {
+		return models.StoredImport{}, err
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO orders (import_id, position, job_id_no, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return models.StoredImport{}, err
+	}
+	defer insert.Close()
+
+	for i, order := range orders {
+		data, err := json.Marshal(order)
+		if err != nil {
+			return models.StoredImport{}, err
+		}
+		if _, err := insert.Exec(stored.ID, i, order.JobIDNo, string(data)); err != nil {
+			return models.StoredImport{}, fmt.Errorf("failed to save order %d of '%s': %w", i+1, sourcePath, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StoredImport{}, err
+	}
+	return stored, nil
+}
+
+// LatestImport returns the most recent import of sourcePath, reporting false
+// when the path has never been imported.
+func (r *OrderStoreRepository) LatestImport(sourcePath string) (models.StoredImport, bool, error) {
+	return latestImport(r.db, sourcePath)
+}
+
+// latestImport reads the most recent import of sourcePath through db, which
+// may be a transaction
+func latestImport(db interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, sourcePath string) (models.StoredImport, bool, error) {
+	row := db.QueryRow(`SELECT `+importColumns+` FROM imports
+		WHERE source_path = ? ORDER BY id DESC LIMIT 1`, sourcePath)
+
+	stored, err := scanImport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.StoredImport{}, false, nil
+	}
+	if err != nil {
+		return models.StoredImport{}, false, fmt.Errorf("failed to read latest import of '%s': %w", sourcePath, err)
+	}
+	return stored, true, nil
+}
+
+// GetImport returns the import with the given ID, reporting false when there
+// is none.
+func (r *OrderStoreRepository) GetImport(importID int64) (models.StoredImport, bool, error) {
+	row := r.db.QueryRow(`SELECT `+importColumns+` FROM imports WHERE id = ?`, importID)
+
+	stored, err := scanImport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.StoredImport{}, false, nil
+	}
+	if err != nil {
+		return models.StoredImport{}, false, fmt.Errorf("failed to read import %d: %w", importID, err)
+	}
+	return stored, true, nil
+}
+
+// ListImports returns the imports of sourcePath, or of every source when it
+// is empty, newest first.
+func (r *OrderStoreRepository) ListImports(sourcePath string) ([]models.StoredImport, error) {
+	rows, err := r.db.Query(`SELECT `+importColumns+` FROM imports
+		WHERE ? = '' OR source_path = ? ORDER BY id DESC`, sourcePath, sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []models.StoredImport{}
+	for rows.Next() {
+		stored, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, stored)
+	}
+	return imports, rows.Err()
+}
+
+// GetImportOrders returns the orders of an import in the order they were read
+func (r *OrderStoreRepository) GetImportOrders(importID int64) ([]models.PurchaseOrder, error) {
+	rows, err := r.db.Query(`SELECT data FROM orders WHERE import_id = ? ORDER BY position`, importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders of import %d: %w", importID, err)
+	}
+	defer rows.Close()
+
+	orders := []models.PurchaseOrder{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var order models.PurchaseOrder
+		if err := json.Unmarshal([]byte(data), &order); err != nil {
+			return nil, fmt.Errorf("failed to decode stored order of import %d: %w", importID, err)
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *OrderStoreRepository) Close() error {
+	return r.db.Close()
+}
+
+func scanImport(row interface{ Scan(...any) error }) (models.StoredImport, error) {
+	var stored models.StoredImport
+	var importedAt string
+	if err := row.Scan(&stored.ID, &stored.SourcePath, &importedAt, &stored.RowCount, &stored.ContentHash); err != nil {
+		return models.StoredImport{}, err
+	}
+	var err error
+	stored.ImportedAt, err = time.Parse(time.RFC3339Nano, importedAt)
+	return stored, err
+}
@@ -0,0 +1,201 @@
+package orderstore
+
+import (
+	"database/sql"
+	"path/filepath"
+	"purchase-record/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stringPtr(s string) *string { return &s }
+func intPtr(i int) *int          { return &i }
+
+func newTestStore(t *testing.T) *OrderStoreRepository {
+	t.Helper()
+	repo, err := NewOrderStoreRepository(filepath.Join(t.TempDir(), "orders.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo.(*OrderStoreRepository)
+}
+
+func TestOrderStoreRepository_SaveAndReadImport(t *testing.T) {
+	store := newTestStore(t)
+	store.Now = func() time.Time { return time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC) }
+
+	deliveryDate := models.NewDate(time.Date(2024, 4, 20, 0, 0, 0, 0, time.UTC))
+	orders := []models.PurchaseOrder{
+		{
+			JobIDNo:      stringPtr("J-1"),
+			Customer:     stringPtr("ลูกค้า A"),
+			Ordered:      intPtr(10),
+			DeliveryDate: &deliveryDate,
+			Shipments:    []models.Shipment{{Date: &deliveryDate, Quantity: intPtr(10), Raw: "20/04/24 (10)"}},
+		},
+		{JobIDNo: stringPtr("J-2")},
+	}
+
+	saved, err := store.SaveImport(`\\share\orders.xlsx`, "hash-1", orders)
+	require.NoError(t, err)
+	assert.NotZero(t, saved.ID)
+	assert.Equal(t, 2, saved.RowCount)
+
+	latest, found, err := store.LatestImport(`\\share\orders.xlsx`)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, saved, latest)
+
+	stored, err := store.GetImportOrders(saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, orders, stored)
+}
+
+func TestOrderStoreRepository_LatestImport(t *testing.T) {
+	store := newTestStore(t)
+
+	_, found, err := store.LatestImport("missing.xlsx")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	first, err := store.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+	require.NoError(t, err)
+	second, err := store.SaveImport("a.xlsx", "hash-2", []models.PurchaseOrder{})
+	require.NoError(t, err)
+	other, err := store.SaveImport("b.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("2")}})
+	require.NoError(t, err)
+
+	latest, found, err := store.LatestImport("a.xlsx")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second.ID, latest.ID)
+
+	orders, err := store.GetImportOrders(latest.ID)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+
+	byID, found, err := store.GetImport(first.ID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, first, byID)
+
+	_, found, err = store.GetImport(999)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	listed, err := store.ListImports("a.xlsx")
+	require.NoError(t, err)
+	assert.Equal(t, []models.StoredImport{second, first}, listed)
+
+	listed, err = store.ListImports("")
+	require.NoError(t, err)
+	assert.Equal(t, []models.StoredImport{other, second, first}, listed)
+}
+
+func TestOrderStoreRepository_SaveImportUnchanged(t *testing.T) {
+	store := newTestStore(t)
+
+	first, err := store.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+	require.NoError(t, err)
+
+	// The same content is not saved twice in a row
+	again, err := store.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	changed, err := store.SaveImport("a.xlsx", "hash-2", []models.PurchaseOrder{{JobIDNo: stringPtr("2")}})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, changed.ID)
+
+	// Going back to earlier content is a new version
+	reverted, err := store.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, reverted.ID)
+
+	// Without a hash every import is saved
+	unhashed, err := store.SaveImport("a.xlsx", "", nil)
+	require.NoError(t, err)
+	unhashedAgain, err := store.SaveImport("a.xlsx", "", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, unhashed.ID, unhashedAgain.ID)
+
+	listed, err := store.ListImports("a.xlsx")
+	require.NoError(t, err)
+	assert.Len(t, listed, 5)
+}
+
+func TestOrderStoreRepository_ConcurrentSaves(t *testing.T) {
+	store := newTestStore(t)
+	saved := make([]models.StoredImport, 10)
+
+	// Hold every save once it has checked for changes, for a while or until
+	// all of them have, to give them the chance to store the same snapshot
+	arrived := make(chan struct{}, len(saved))
+	store.Now = func() time.Time {
+		arrived <- struct{}{}
+		deadline := time.After(50 * time.Millisecond)
+		for len(arrived) < len(saved) {
+			select {
+			case <-deadline:
+				return time.Now()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return time.Now()
+	}
+
+	// Concurrent imports of the same unchanged workbook store one snapshot
+	var wg sync.WaitGroup
+	for i := range saved {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			saved[i], err = store.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	listed, err := store.ListImports("a.xlsx")
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	for _, stored := range saved {
+		assert.Equal(t, listed[0].ID, stored.ID)
+	}
+}
+
+func TestOrderStoreRepository_Migrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "orders.db")
+
+	repo, err := NewOrderStoreRepository(dbPath)
+	require.NoError(t, err)
+	_, err = repo.SaveImport("a.xlsx", "hash-1", []models.PurchaseOrder{{JobIDNo: stringPtr("1")}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// Reopening keeps the data and does not apply migrations again
+	repo, err = NewOrderStoreRepository(dbPath)
+	require.NoError(t, err)
+	_, found, err := repo.LatestImport("a.xlsx")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	var applied int
+	require.NoError(t, repo.(*OrderStoreRepository).db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+	require.NoError(t, repo.Close())
+
+	// A database from a newer build is refused
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, '')`, len(migrations)+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = NewOrderStoreRepository(dbPath)
+	assert.ErrorContains(t, err, "newer than this build supports")
+}
@@ -0,0 +1,175 @@
+package orderwatch
+
+import (
+	"context"
+	"log"
+	"os"
+	"purchase-record/config"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/importexcel"
+	"sync"
+	"time"
+)
+
+// IWatcher re-imports the workbooks listed in the settings workbook whenever
+// they change, so their backups, snapshots and cached orders stay fresh
+// without anyone asking for them
+type IWatcher interface {
+	Run(ctx context.Context)
+	Poll()
+	Status() models.WatchStatus
+}
+
+// Watcher polls the listed workbooks rather than waiting for file system
+// events, which mounted network shares do not deliver reliably
+type Watcher struct {
+	// Settings is the settings workbook listing the paths to watch
+	Settings string
+	// Interval is the time between two passes over the paths
+	Interval           time.Duration
+	SettingPathService importexcel.ISettingPathService
+	// Loader reads and backs up the workbooks as a request for them would,
+	// and its service parses them
+	Loader *importexcel.WorkbookLoader
+	Now    func() time.Time
+
+	// polling keeps two passes from overlapping
+	polling sync.Mutex
+	mutex   sync.Mutex
+	status  models.WatchStatus
+}
+
+// NewWatcher applies the watcher configuration to the paths listed in the
+// settingsPath workbook, importing through loader so the watcher shares the
+// requests' limits, backups, store and cache. It returns nil when no settings
+// workbook or interval is configured, which disables watching.
+func NewWatcher(cfg config.WatchConfig, settingsPath string, settings importexcel.ISettingPathService, loader *importexcel.WorkbookLoader) IWatcher {
+	if settingsPath == "" || cfg.Interval <= 0 {
+		return nil
+	}
+	return &Watcher{
+		Settings:           settingsPath,
+		Interval:           cfg.Interval,
+		SettingPathService: settings,
+		Loader:             loader,
+		Now:                time.Now,
+	}
+}
+
+// Run checks the listed workbooks right away and then every Interval until ctx
+// is done
+func (w *Watcher) Run(ctx context.Context) {
+	log.Printf("Watching the workbooks listed in '%s' every %s", w.Settings, w.Interval)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		w.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the paths from the settings workbook and re-imports each one
+// whose size or modification time changed since its last import, or whose
+// last check failed. Paths no longer listed are forgotten.
+func (w *Watcher) Poll() {
+	w.polling.Lock()
+	defer w.polling.Unlock()
+
+	checkedAt := w.Now()
+	settings, err := w.SettingPathService.GetSettingPath(w.Settings, models.SheetSelector{})
+	if err != nil {
+		log.Printf("Failed to read the watched paths from '%s': %v", w.Settings, err)
+		w.mutex.Lock()
+		w.status.CheckedAt = &checkedAt
+		w.status.Error = err.Error()
+		w.mutex.Unlock()
+		return
+	}
+
+	previous := map[string]models.WatchedPath{}
+	for _, watched := range w.Status().Paths {
+		previous[watched.Path] = watched
+	}
+	paths := make([]models.WatchedPath, 0, len(settings))
+	for _, setting := range settings {
+		if setting.Path == "" {
+			continue
+		}
+		paths = append(paths, w.check(setting, previous[setting.Path]))
+	}
+
+	w.mutex.Lock()
+	w.status.CheckedAt = &checkedAt
+	w.status.Error = ""
+	w.status.Paths = paths
+	w.mutex.Unlock()
+}
+
+// Status returns the outcome of the last pass
+func (w *Watcher) Status() models.WatchStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	status := w.status
+	status.Settings = w.Settings
+	status.IntervalSeconds = int64(w.Interval / time.Second)
+	status.Paths = append([]models.WatchedPath{}, w.status.Paths...)
+	return status
+}
+
+// check re-imports the workbook of setting when it differs from the version
+// of its previous import, with the options a request following the settings
+// uses: loaded and backed up by the Loader, then parsed into the store and
+// the cache
+func (w *Watcher) check(setting models.SettingExcelData, previous models.WatchedPath) models.WatchedPath {
+	checkedAt := w.Now()
+	watched := previous
+	watched.Path, watched.Name, watched.Sheet = setting.Path, setting.Name, setting.Sheet
+	watched.CheckedAt = &checkedAt
+
+	info, err := os.Stat(setting.Path)
+	if err != nil {
+		return failed(watched, err)
+	}
+	if previous.Error == "" && previous.ModTime != nil && info.Size() == previous.Size && info.ModTime().Equal(*previous.ModTime) {
+		return watched
+	}
+
+	opts := setting.ImportOptions()
+	loaded, err := w.Loader.Load(setting.Path, opts)
+	if err != nil {
+		return failed(watched, err)
+	}
+	watched.BackupError = loaded.BackupError
+
+	count, err := w.Loader.Service.ImportWorkbook(loaded.Workbook, opts)
+	if err != nil {
+		return failed(watched, err)
+	}
+
+	importedAt := w.Now()
+	modTime := loaded.Workbook.ModTime
+	watched.ImportedAt = &importedAt
+	watched.Size = loaded.Workbook.Size
+	watched.ModTime = &modTime
+	if loaded.Workbook.Hash != "" {
+		// Cached orders come without the content, whose hash is unchanged
+		watched.ContentHash = loaded.Workbook.Hash
+	}
+	watched.RowCount = count
+	watched.Imports++
+	watched.Error = ""
+	log.Printf("Re-imported '%s': %d orders", setting.Path, count)
+	return watched
+}
+
+// failed records why a path could not be checked
+func failed(watched models.WatchedPath, err error) models.WatchedPath {
+	log.Printf("Failed to re-import '%s': %v", watched.Path, err)
+	watched.Error = err.Error()
+	return watched
+}
@@ -0,0 +1,155 @@
+package orderwatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"purchase-record/config"
+	"purchase-record/internal/models"
+	"purchase-record/internal/purchaseorders/importexcel"
+	"purchase-record/internal/purchaseorders/importexcel/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestWatcher(t *testing.T) (*Watcher, *mocks.ISettingPathService, *mocks.INetworkPathService) {
+	config.CF.Backup.Dir = t.TempDir()
+	settings := new(mocks.ISettingPathService)
+	service := new(mocks.INetworkPathService)
+	service.On("ValidateWorkbook", mock.Anything, mock.Anything).Return(nil).Maybe()
+	service.On("CachedOrders", mock.Anything, mock.Anything).Return(nil, false).Maybe()
+	watcher := &Watcher{
+		Settings:           "settings.xlsx",
+		Interval:           time.Minute,
+		SettingPathService: settings,
+		Loader:             importexcel.NewWorkbookLoader(service, 0),
+		Now:                func() time.Time { return now },
+	}
+	return watcher, settings, service
+}
+
+// workbookAt matches the workbook read from path with the given content
+func workbookAt(path, content string) any {
+	return mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == path && string(workbook.Data) == content
+	})
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	watcher, settings, service := newTestWatcher(t)
+	dir := t.TempDir()
+	orders := filepath.Join(dir, "orders.xlsx")
+	missing := filepath.Join(dir, "missing.xlsx")
+	require.NoError(t, os.WriteFile(orders, []byte("v1"), 0644))
+
+	settings.On("GetSettingPath", "settings.xlsx", models.SheetSelector{}).Return([]models.SettingExcelData{
+		{Path: orders, Name: "Orders", Sheet: "PO"},
+		{Path: missing, Name: "Missing"},
+	}, nil)
+	opts := models.ImportOptions{SheetSelector: models.SheetSelector{Sheet: "PO"}}
+	service.On("ImportWorkbook", workbookAt(orders, "v1"), opts).Return(int64(3), nil).Once()
+
+	watcher.Poll()
+	status := watcher.Status()
+	assert.Equal(t, "settings.xlsx", status.Settings)
+	assert.Equal(t, int64(60), status.IntervalSeconds)
+	assert.Equal(t, now, *status.CheckedAt)
+	require.Len(t, status.Paths, 2)
+	assert.Equal(t, "Orders", status.Paths[0].Name)
+	assert.Equal(t, "PO", status.Paths[0].Sheet)
+	assert.Equal(t, int64(3), status.Paths[0].RowCount)
+	assert.Equal(t, int64(1), status.Paths[0].Imports)
+	assert.Equal(t, now, *status.Paths[0].ImportedAt)
+	assert.Empty(t, status.Paths[0].Error)
+	assert.Nil(t, status.Paths[1].ImportedAt)
+	assert.NotEmpty(t, status.Paths[1].Error)
+
+	// The workbook was backed up as it was imported
+	backups, err := os.ReadDir(config.CF.Backup.Dir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, backups)
+
+	// An unchanged workbook is not imported again
+	watcher.Poll()
+	assert.Equal(t, int64(1), watcher.Status().Paths[0].Imports)
+
+	// A modified one is, and so is one that appeared since the last check
+	require.NoError(t, os.WriteFile(orders, []byte("v2"), 0644))
+	require.NoError(t, os.Chtimes(orders, now, now.Add(time.Hour)))
+	require.NoError(t, os.WriteFile(missing, []byte("new"), 0644))
+	service.On("ImportWorkbook", workbookAt(orders, "v2"), opts).Return(int64(4), nil).Once()
+	service.On("ImportWorkbook", workbookAt(missing, "new"), models.ImportOptions{}).Return(int64(1), nil).Once()
+
+	watcher.Poll()
+	status = watcher.Status()
+	assert.Equal(t, int64(2), status.Paths[0].Imports)
+	assert.Equal(t, int64(4), status.Paths[0].RowCount)
+	assert.Equal(t, int64(1), status.Paths[1].Imports)
+	assert.Empty(t, status.Paths[1].Error)
+	service.AssertExpectations(t)
+}
+
+func TestWatcher_Failures(t *testing.T) {
+	watcher, settings, service := newTestWatcher(t)
+	orders := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(orders, []byte("v1"), 0644))
+	listed := []models.SettingExcelData{{Path: orders, Name: "Orders"}}
+
+	// A failed import is retried on the next pass even though nothing changed
+	settings.On("GetSettingPath", "settings.xlsx", models.SheetSelector{}).Return(listed, nil).Twice()
+	service.On("ImportWorkbook", workbookAt(orders, "v1"), models.ImportOptions{}).Return(int64(0), errors.New("no header row")).Once()
+	service.On("ImportWorkbook", workbookAt(orders, "v1"), models.ImportOptions{}).Return(int64(2), nil).Once()
+
+	watcher.Poll()
+	assert.Equal(t, "no header row", watcher.Status().Paths[0].Error)
+	watcher.Poll()
+	assert.Empty(t, watcher.Status().Paths[0].Error)
+	assert.Equal(t, int64(1), watcher.Status().Paths[0].Imports)
+
+	// An unreadable settings workbook keeps the paths of the pass before
+	settings.On("GetSettingPath", "settings.xlsx", models.SheetSelector{}).Return(nil, errors.New("settings unreachable")).Once()
+	watcher.Poll()
+	status := watcher.Status()
+	assert.Equal(t, "settings unreachable", status.Error)
+	require.Len(t, status.Paths, 1)
+	assert.Equal(t, int64(2), status.Paths[0].RowCount)
+	service.AssertExpectations(t)
+}
+
+func TestWatcher_Cached(t *testing.T) {
+	config.CF.Backup.Dir = t.TempDir()
+	orders := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, os.WriteFile(orders, []byte("v1"), 0644))
+	cached := []models.PurchaseOrder{{}, {}}
+
+	// Orders a request already cached are counted without reading the file
+	settings := new(mocks.ISettingPathService)
+	settings.On("GetSettingPath", "settings.xlsx", models.SheetSelector{}).Return([]models.SettingExcelData{{Path: orders, Name: "Orders"}}, nil)
+	service := new(mocks.INetworkPathService)
+	service.On("CachedOrders", mock.Anything, models.ImportOptions{}).Return(cached, true)
+	service.On("ImportWorkbook", mock.MatchedBy(func(workbook models.Workbook) bool {
+		return workbook.Path == orders && !workbook.Loaded() && len(workbook.Orders) == 2
+	}), models.ImportOptions{}).Return(int64(2), nil).Once()
+	watcher := &Watcher{Settings: "settings.xlsx", Interval: time.Minute, SettingPathService: settings, Loader: importexcel.NewWorkbookLoader(service, 0), Now: time.Now}
+
+	watcher.Poll()
+	watched := watcher.Status().Paths[0]
+	assert.Equal(t, int64(2), watched.RowCount)
+	assert.Equal(t, int64(2), watched.Size)
+	service.AssertExpectations(t)
+	service.AssertNotCalled(t, "ValidateWorkbook", mock.Anything, mock.Anything)
+}
+
+func TestNewWatcher(t *testing.T) {
+	assert.Nil(t, NewWatcher(config.WatchConfig{Interval: time.Minute}, "", nil, nil))
+	assert.Nil(t, NewWatcher(config.WatchConfig{}, "settings.xlsx", nil, nil))
+
+	watcher := NewWatcher(config.WatchConfig{Interval: time.Minute}, "settings.xlsx", nil, nil)
+	assert.Equal(t, models.WatchStatus{Settings: "settings.xlsx", IntervalSeconds: 60, Paths: []models.WatchedPath{}}, watcher.Status())
+}
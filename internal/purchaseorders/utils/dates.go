@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// buddhistEraOffset is the difference between Thai Buddhist-era and Gregorian years.
+const buddhistEraOffset = 543
+
+// Largest serial Excel accepts, 9999-12-31
+const maxExcelSerial = 2958465
+
+// Layouts tried in order once separators are normalized to "/" and years are
+// expanded to four Gregorian digits. Day-first comes first because the
+// workbooks are Thai.
+var dateLayouts = []string{"2/1/2006", "2006/1/2", "2/Jan/2006", "2/January/2006"}
+
+var dateSeparators = regexp.MustCompile(`[\s\-.,/]+`)
+
+// timeOfDay matches a trailing time such as " 10:00" or "T08:30:00Z", from the
+// space or "T" before its hours
+var timeOfDay = regexp.MustCompile(`(?:T|\s+)\d{1,2}:\d{2}.*$`)
+
+// thaiMonths maps Thai month abbreviations and names to English ones.
+var thaiMonths = strings.NewReplacer(
+	"ม.ค.", " Jan ", "ก.พ.", " Feb ", "มี.ค.", " Mar ", "เม.ย.", " Apr ",
+	"พ.ค.", " May ", "มิ.ย.", " Jun ", "ก.ค.", " Jul ", "ส.ค.", " Aug ",
+	"ก.ย.", " Sep ", "ต.ค.", " Oct ", "พ.ย.", " Nov ", "ธ.ค.", " Dec ",
+	"มกราคม", " Jan ", "กุมภาพันธ์", " Feb ", "มีนาคม", " Mar ", "เมษายน", " Apr ",
+	"พฤษภาคม", " May ", "มิถุนายน", " Jun ", "กรกฎาคม", " Jul ", "สิงหาคม", " Aug ",
+	"กันยายน", " Sep ", "ตุลาคม", " Oct ", "พฤศจิกายน", " Nov ", "ธันวาคม", " Dec ",
+)
+
+// ParseDate converts a cell into a date. It accepts Excel serial numbers,
+// day-first text such as "12/03/2024", "5-Mar-24" or "2024-03-12", Thai month
+// names, and Thai Buddhist-era years such as "12/03/2567".
+func ParseDate(s string) (models.Date, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return models.Date{}, fmt.Errorf("empty date")
+	}
+
+	if serial, err := strconv.ParseFloat(s, 64); err == nil {
+		// ParseFloat also accepts "NaN" and "Inf", which no range check catches
+		if math.IsNaN(serial) || math.IsInf(serial, 0) {
+			return models.Date{}, fmt.Errorf("'%s' is not a valid Excel date", s)
+		}
+		if serial < 1 || serial > maxExcelSerial {
+			return models.Date{}, fmt.Errorf("'%s' is outside the Excel date range", s)
+		}
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return models.Date{}, fmt.Errorf("'%s' is not a valid Excel date: %w", s, err)
+		}
+		return models.NewDate(t), nil
+	}
+
+	text := strings.TrimSpace(thaiMonths.Replace(s))
+	// Drop a trailing time of day such as "2024-03-12 00:00:00" or
+	// "12 Mar 2024 10:00"
+	if loc := timeOfDay.FindStringIndex(text); loc != nil && loc[0] > 0 {
+		text = text[:loc[0]]
+	}
+	parts := strings.Split(dateSeparators.ReplaceAllString(text, "/"), "/")
+	if len(parts) != 3 {
+		return models.Date{}, fmt.Errorf("'%s' is not a recognized date", s)
+	}
+	// Convert the year before parsing so that 29 February of a Buddhist-era
+	// leap year is not rejected
+	if year, err := strconv.Atoi(parts[0]); err == nil && len(parts[0]) == 4 {
+		parts[0] = strconv.Itoa(gregorianYear(year))
+	} else if year, err := strconv.Atoi(parts[2]); err == nil && (len(parts[2]) == 2 || len(parts[2]) == 4) {
+		if len(parts[2]) == 2 {
+			year = expandTwoDigitYear(year, time.Now())
+		}
+		parts[2] = strconv.Itoa(gregorianYear(year))
+	}
+	text = strings.Join(parts, "/")
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return models.NewDate(t), nil
+		}
+	}
+
+	return models.Date{}, fmt.Errorf("'%s' is not a recognized date", s)
+}
+
+// expandTwoDigitYear reads yy as 20YY unless that lies more than ten years
+// ahead of now, in which case it is taken as 19YY.
+func expandTwoDigitYear(yy int, now time.Time) int {
+	if 2000+yy > now.Year()+10 {
+		return 1900 + yy
+	}
+	return 2000 + yy
+}
+
+// gregorianYear converts Buddhist-era years, which are 543 years ahead.
+func gregorianYear(year int) int {
+	if year >= 2400 {
+		return year - buddhistEraOffset
+	}
+	return year
+}
+
+// DateOrNil returns a pointer to the parsed date, or nil when s is empty or not a date.
+func DateOrNil(s string) *models.Date {
+	date, err := ParseDate(s)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{name: "excel serial", input: "45363", expected: "2024-03-12"},
+		{name: "excel serial with time", input: "45363.75", expected: "2024-03-12"},
+		{name: "day first with slashes", input: "12/03/2024", expected: "2024-03-12"},
+		{name: "day first without padding", input: "2/3/2024", expected: "2024-03-02"},
+		{name: "two digit year", input: "12/03/24", expected: "2024-03-12"},
+		{name: "day month abbreviation", input: "5-Mar-24", expected: "2024-03-05"},
+		{name: "day month name", input: "5 March 2024", expected: "2024-03-05"},
+		{name: "iso date", input: "2024-03-12", expected: "2024-03-12"},
+		{name: "iso date with time", input: "2024-03-12 08:30:00", expected: "2024-03-12"},
+		{name: "iso date and time", input: "2024-03-12T08:30:00Z", expected: "2024-03-12"},
+		{name: "month name with time", input: "12 Mar 2024 10:00", expected: "2024-03-12"},
+		{name: "thai month with time", input: "12 มี.ค. 2567 08:30:00", expected: "2024-03-12"},
+		{name: "dotted", input: "12.03.2024", expected: "2024-03-12"},
+		{name: "buddhist era", input: "12/03/2567", expected: "2024-03-12"},
+		{name: "buddhist era leap day", input: "29/02/2567", expected: "2024-02-29"},
+		{name: "two digit year of the previous century", input: "31/12/99", expected: "1999-12-31"},
+		{name: "day month abbreviation of the previous century", input: "12-Mar-99", expected: "1999-03-12"},
+		{name: "thai month abbreviation", input: "12 มี.ค. 2567", expected: "2024-03-12"},
+		{name: "thai month name", input: "1 มกราคม 2568", expected: "2025-01-01"},
+		{name: "surrounding whitespace", input: "  12/03/2024 ", expected: "2024-03-12"},
+		{name: "empty", input: "", expectedError: "empty date"},
+		{name: "invalid day", input: "31/02/2024", expectedError: "'31/02/2024' is not a recognized date"},
+		{name: "text", input: "pending", expectedError: "'pending' is not a recognized date"},
+		{name: "serial out of range", input: "-3", expectedError: "outside the Excel date range"},
+		{name: "not a number", input: "NaN", expectedError: "'NaN' is not a valid Excel date"},
+		{name: "infinity", input: "+Inf", expectedError: "'+Inf' is not a valid Excel date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := ParseDate(tt.input)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, date.String())
+		})
+	}
+}
+
+func TestExpandTwoDigitYear(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 2000, expandTwoDigitYear(0, now))
+	assert.Equal(t, 2024, expandTwoDigitYear(24, now))
+	// Ten years ahead is the last year read in this century
+	assert.Equal(t, 2036, expandTwoDigitYear(36, now))
+	assert.Equal(t, 1937, expandTwoDigitYear(37, now))
+	assert.Equal(t, 1999, expandTwoDigitYear(99, now))
+}
+
+func TestDateOrNil(t *testing.T) {
+	assert.Nil(t, DateOrNil(""))
+	assert.Nil(t, DateOrNil("not a date"))
+	assert.Equal(t, "2024-01-01", DateOrNil("2024-01-01").String())
+}
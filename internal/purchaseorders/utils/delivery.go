@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"purchase-record/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// deliveryQuantity reads the inside of a "(5 pcs)" group: a count with
+// optional thousands separators or decimals followed by an optional unit.
+var deliveryQuantity = regexp.MustCompile(`^([\d,]*\.?\d+)\s*(.*)$`)
+
+// ParseDeliveryLog splits a delivery cell such as "12/03/24 (5 pcs), 20/03/24 (3)"
+// into shipments. Entries are separated by commas, semicolons or line breaks
+// outside parentheses. An entry becomes a shipment when its date or its
+// quantity can be read; entries or parts of entries that cannot be read are
+// returned as fragments.
+func ParseDeliveryLog(cell string) ([]models.Shipment, []string) {
+	var shipments []models.Shipment
+	var fragments []string
+
+	for _, entry := range splitDeliveryEntries(cell) {
+		shipment := models.Shipment{Raw: entry}
+		dateText, quantityText := splitDeliveryEntry(entry)
+
+		unread := false
+		if dateText != "" {
+			if date, err := ParseDate(dateText); err == nil {
+				shipment.Date = &date
+			} else {
+				unread = true
+			}
+		}
+		if quantityText != "" {
+			if quantity, unit, err := parseDeliveryQuantity(quantityText); err == nil {
+				shipment.Quantity = &quantity
+				shipment.Unit = unit
+			} else {
+				unread = true
+			}
+		}
+
+		if shipment.Date == nil && shipment.Quantity == nil {
+			fragments = append(fragments, entry)
+			continue
+		}
+		if unread {
+			fragments = append(fragments, entry)
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, fragments
+}
+
+// LastShipmentDate returns the latest shipment date, or nil when no shipment
+// has one.
+func LastShipmentDate(shipments []models.Shipment) *models.Date {
+	var last *models.Date
+	for _, shipment := range shipments {
+		if shipment.Date != nil && (last == nil || shipment.Date.After(last.Time)) {
+			last = shipment.Date
+		}
+	}
+	return last
+}
+
+// ShippedQuantity adds up the quantities of the shipments.
+func ShippedQuantity(shipments []models.Shipment) int {
+	total := 0
+	for _, shipment := range shipments {
+		if shipment.Quantity != nil {
+			total += *shipment.Quantity
+		}
+	}
+	return total
+}
+
+// splitDeliveryEntries cuts the cell at separators that are not inside
+// parentheses, so "(1,200 pcs)" stays whole, and drops blank entries.
+func splitDeliveryEntries(cell string) []string {
+	var entries []string
+	depth, start := 0, 0
+	add := func(end int) {
+		if entry := strings.TrimSpace(cell[start:end]); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	for i, r := range cell {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0 && (r == ',' || r == ';' || r == '\n'):
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(cell))
+	return entries
+}
+
+// splitDeliveryEntry separates the text outside parentheses from the text
+// inside them.
+func splitDeliveryEntry(entry string) (string, string) {
+	open := strings.Index(entry, "(")
+	if open < 0 {
+		return strings.TrimSpace(entry), ""
+	}
+	close := strings.LastIndex(entry, ")")
+	if close < open {
+		close = len(entry)
+	}
+
+	outside := entry[:open]
+	if close < len(entry) {
+		outside += " " + entry[close+1:]
+	}
+	return strings.TrimSpace(outside), strings.TrimSpace(entry[open+1 : close])
+}
+
+func parseDeliveryQuantity(s string) (int, string, error) {
+	match := deliveryQuantity.FindStringSubmatch(s)
+	if match == nil {
+		return 0, "", fmt.Errorf("'%s' is not a quantity", s)
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("'%s' is not a quantity", s)
+	}
+	return int(math.Round(value)), strings.TrimSpace(match[2]), nil
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"purchase-record/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeliveryLog(t *testing.T) {
+	quantity := func(n int) *int { return &n }
+
+	tests := []struct {
+		name              string
+		cell              string
+		expectedShipments []models.Shipment
+		expectedFragments []string
+	}{
+		{
+			name: "dated quantities with units",
+			cell: "12/03/24 (5 pcs), 20/03/24 (3)",
+			expectedShipments: []models.Shipment{
+				{Date: DateOrNil("2024-03-12"), Quantity: quantity(5), Unit: "pcs", Raw: "12/03/24 (5 pcs)"},
+				{Date: DateOrNil("2024-03-20"), Quantity: quantity(3), Raw: "20/03/24 (3)"},
+			},
+		},
+		{
+			name: "line breaks and separators inside parentheses",
+			cell: "12/03/2567 (1,200 ชิ้น)\n20/03/2567(8.0)",
+			expectedShipments: []models.Shipment{
+				{Date: DateOrNil("2024-03-12"), Quantity: quantity(1200), Unit: "ชิ้น", Raw: "12/03/2567 (1,200 ชิ้น)"},
+				{Date: DateOrNil("2024-03-20"), Quantity: quantity(8), Raw: "20/03/2567(8.0)"},
+			},
+		},
+		{
+			name: "date without quantity",
+			cell: "12/03/24; ",
+			expectedShipments: []models.Shipment{
+				{Date: DateOrNil("2024-03-12"), Raw: "12/03/24"},
+			},
+		},
+		{
+			name: "partly readable entry is kept and reported",
+			cell: "soon (5)",
+			expectedShipments: []models.Shipment{
+				{Quantity: quantity(5), Raw: "soon (5)"},
+			},
+			expectedFragments: []string{"soon (5)"},
+		},
+		{
+			name: "unreadable entries",
+			cell: "12/03/24 (5), waiting for stock, 31/02/24 (partial)",
+			expectedShipments: []models.Shipment{
+				{Date: DateOrNil("2024-03-12"), Quantity: quantity(5), Raw: "12/03/24 (5)"},
+			},
+			expectedFragments: []string{"waiting for stock", "31/02/24 (partial)"},
+		},
+		{
+			name: "empty cell",
+			cell: "  ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shipments, fragments := ParseDeliveryLog(tt.cell)
+
+			assert.Equal(t, tt.expectedShipments, shipments)
+			assert.Equal(t, tt.expectedFragments, fragments)
+		})
+	}
+}
+
+func TestLastShipmentDateAndShippedQuantity(t *testing.T) {
+	shipments, _ := ParseDeliveryLog("20/03/24 (3), 12/03/24 (5), pending (2)")
+
+	assert.Equal(t, "2024-03-20", LastShipmentDate(shipments).String())
+	assert.Equal(t, 10, ShippedQuantity(shipments))
+	assert.Nil(t, LastShipmentDate(nil))
+}
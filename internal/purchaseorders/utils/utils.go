@@ -1,6 +1,9 @@
 package utils
 
-import "strconv"
+import (
+	"math"
+	"strconv"
+)
 
 // stringOrNil returns a pointer to the string if it's not empty, otherwise nil.
 func StringOrNil(s string) *string {
@@ -10,15 +13,32 @@ func StringOrNil(s string) *string {
 	return &s
 }
 
+// intEpsilon is how far, relative to its size, a raw cell value may be from a
+// whole number and still be read as one. Formula results such as 0.1*30 are
+// stored as 2.9999999999999996.
+const intEpsilon = 1e-9
+
 // IntOrNil returns a pointer to the integer if the string is a valid number, otherwise nil.
+// A decimal within intEpsilon of a whole number is rounded to it.
 func IntOrNil(s string) *int {
 	if s == "" {
 		return nil
 	}
 	value, err := strconv.Atoi(s)
-	if err != nil {
+	if err == nil {
+		return &value
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	rounded := math.Round(f)
+	if math.Abs(f-rounded) > intEpsilon*math.Max(1, math.Abs(f)) ||
+		rounded < math.MinInt || rounded >= math.MaxInt {
 		return nil
 	}
+	value = int(rounded)
 	return &value
 }
 
@@ -1,14 +1,29 @@
 package router
 
 import (
+	"context"
 	"purchase-record/internal/handlers/purchaseorderhandler"
+	"purchase-record/internal/purchaseorders/orderstore"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutePurchaseOrder(r *gin.Engine) {
-	handler := purchaseorderhandler.NewHandler()
+// RegisterRoutePurchaseOrder adds the purchase order routes. Imports are kept
+// in store unless it is nil. The watched workbooks, if configured, are
+// re-imported in the background until ctx is done.
+func RegisterRoutePurchaseOrder(ctx context.Context, r *gin.Engine, store orderstore.IOrderStoreRepository) {
+	handler := purchaseorderhandler.NewHandlerWithStore(store)
+	go handler.Watch(ctx)
 	group := r.Group("/purchaseorders")
 	group.POST("", handler.GetOrdersFromNetworkPath)
+	group.POST("/summary", handler.GetSummary)
+	group.POST("/export", handler.ExportOrders)
+	group.POST("/upload", handler.UploadOrders)
+	group.GET("/snapshots", handler.ListSnapshots)
+	group.GET("/snapshots/diff", handler.DiffSnapshots)
+	group.GET("/cache", handler.GetCacheStats)
+	group.DELETE("/cache", handler.InvalidateCache)
+	group.GET("/watch", handler.GetWatchStatus)
+	group.GET("/sheets", handler.GetSheets)
 	group.GET("/setting", handler.GetSettingPath)
 }
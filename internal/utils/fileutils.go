@@ -1,48 +1,478 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"purchase-record/config"
+	"purchase-record/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// backupTimeLayout is the UTC timestamp in backup file names
+const backupTimeLayout = "20060102-150405.000"
+
+// backupHashLength is how many hex digits of the content hash a name carries
+const backupHashLength = 16
+
+// backupIndexName is the file in the backup directory that maps each source
+// folder back to the path it was made from
+const backupIndexName = "index.json"
+
+// backupIndexMutex serializes updates of the index
+var backupIndexMutex sync.Mutex
+
+// sourceMutexes holds a mutex per backup folder, so backups of one source are
+// made one at a time while other sources go ahead
+var sourceMutexes sync.Map
+
+// ErrSourceTooLarge is returned by ReadSource for files over the size limit
+var ErrSourceTooLarge = errors.New("source file is too large")
+
+// BackupVersion is one backup of a source file, named
+// "<name>_<timestamp>_<hash><ext>" such as "PO_20240501-083000.000_3f2a9b7c01d4e5f6.xlsx".
+type BackupVersion struct {
+	Path      string
+	CreatedAt time.Time
+	Hash      string
+}
+
+// BackupValidator checks the content of a source file before it is kept as a
+// backup, such as whether a workbook opens and has the expected headers
+type BackupValidator func(data []byte) error
+
+// Backups keeps timestamped versions of source files in Dir and removes
+// versions beyond KeepVersions or older than KeepDays. A limit of 0 is no
+// limit, and the newest version is never removed.
+//
+// Each source has its own folder named by SourceKey, so files with the same
+// name in different folders never share backups. Dir/index.json maps the
+// folders back to the source paths, and may name the source of a backup left
+// by an earlier release; see TakeInLegacy.
+type Backups struct {
+	Dir          string
+	KeepVersions int
+	KeepDays     int
+	Now          func() time.Time
+}
+
+// NewBackups applies the backup configuration, using ./backup when it names
+// no directory
+func NewBackups(cfg config.BackupConfig) *Backups {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "backup"
+	}
+	return &Backups{Dir: dir, KeepVersions: cfg.KeepVersions, KeepDays: cfg.KeepDays, Now: time.Now}
+}
+
+// TakeInLegacyBackup takes in the backup of the source file made by an
+// earlier release, when the backup index records its source and validate
+// accepts it
+func TakeInLegacyBackup(sourcePath string, validate BackupValidator) error {
+	return NewBackups(config.CF.Backup).TakeInLegacy(sourcePath, validate)
+}
+
 // GetLatestBackupFile returns the path to the latest backup file for the given source file
 func GetLatestBackupFile(sourcePath string) (string, error) {
-	backupDir := "backup"
-	fileName := filepath.Base(sourcePath)
-	backupPath := filepath.Join(backupDir, fileName)
+	return NewBackups(config.CF.Backup).Latest(sourcePath)
+}
 
-	// Check if backup file exists
-	_, err := os.Stat(backupPath)
+// GetLatestBackup returns the latest valid backup of the source file with the
+// time it was made
+func GetLatestBackup(sourcePath string) (BackupVersion, error) {
+	return NewBackups(config.CF.Backup).LatestVersion(sourcePath)
+}
+
+// BackupFile saves data, the content of the source file, as a new version
+// unless it is unchanged since the latest backup or rejected by validate
+func BackupFile(sourcePath string, data []byte, validate BackupValidator) (string, error) {
+	return NewBackups(config.CF.Backup).BackupData(sourcePath, data, validate)
+}
+
+// ReadSource reads the whole file at path in one pass. A file larger than
+// maxSize bytes is refused without reading it to the end; 0 is no limit.
+func ReadSource(path string, maxSize int64) (models.Workbook, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("no backup file found: %v", err)
+		return models.Workbook{}, err
 	}
+	defer file.Close()
 
-	return backupPath, nil
-}
+	info, err := file.Stat()
+	if err != nil {
+		return models.Workbook{}, err
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		return models.Workbook{}, sourceTooLarge(path, maxSize)
+	}
 
-// BackupFile copies a file to a backup folder, replacing any existing file with the same name
-func BackupFile(sourcePath string) (string, error) {
-	// Create backup directory if it doesn't exist
-	backupDir := "backup"
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	// The file may still grow while it is read
+	var reader io.Reader = file
+	if maxSize > 0 {
+		reader = io.LimitReader(file, maxSize+1)
 	}
+	buffer := bytes.NewBuffer(make([]byte, 0, info.Size()+bytes.MinRead))
+	if _, err := buffer.ReadFrom(reader); err != nil {
+		return models.Workbook{}, fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	if maxSize > 0 && int64(buffer.Len()) > maxSize {
+		return models.Workbook{}, sourceTooLarge(path, maxSize)
+	}
+
+	data := buffer.Bytes()
+	return models.Workbook{
+		Path:    path,
+		Size:    int64(len(data)),
+		ModTime: info.ModTime(),
+		Hash:    contentHash(data),
+		Data:    data,
+	}, nil
+}
 
-	// Use the original filename for backup
-	fileName := filepath.Base(sourcePath)
-	backupPath := filepath.Join(backupDir, fileName)
+func sourceTooLarge(path string, maxSize int64) error {
+	return fmt.Errorf("%w: '%s' is over the limit of %d bytes", ErrSourceTooLarge, filepath.Base(path), maxSize)
+}
 
-	// Read source file
+// Backup reads sourcePath and saves it like BackupData
+func (b *Backups) Backup(sourcePath string, validate BackupValidator) (string, error) {
 	sourceData, err := os.ReadFile(sourcePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read source file: %v", err)
 	}
+	return b.BackupData(sourcePath, sourceData, validate)
+}
+
+// BackupData saves sourceData, the content of sourcePath, as a new version and
+// prunes old versions. When the newest version already holds the same content
+// nothing is written and its path is returned. Content that validate rejects
+// is not saved, leaving the previous versions as they are; a nil validate
+// accepts any content.
+func (b *Backups) BackupData(sourcePath string, sourceData []byte, validate BackupValidator) (string, error) {
+	hash := contentHash(sourceData)
+
+	// A concurrent backup of the same content finds the version written by
+	// the first one instead of writing its own
+	unlock := b.lockSource(sourcePath)
+	defer unlock()
 
-	// Write to backup file (will replace if exists)
-	if err := os.WriteFile(backupPath, sourceData, 0644); err != nil {
+	// A legacy backup that cannot be taken in leaves the new one to be written
+	_ = b.takeInLegacy(sourcePath, validate)
+	versions, err := b.Versions(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) > 0 && versions[0].Hash == hash[:backupHashLength] && b.valid(versions[0]) {
+		return versions[0].Path, nil
+	}
+
+	if validate != nil {
+		if err := validate(sourceData); err != nil {
+			return "", fmt.Errorf("source file failed validation, the previous backup is kept: %v", err)
+		}
+	}
+
+	if err := b.register(sourcePath); err != nil {
+		return "", err
+	}
+	backupPath := b.versionPath(sourcePath, b.Now(), hash)
+
+	if err := writeFileAtomic(backupPath, sourceData); err != nil {
 		return "", fmt.Errorf("failed to write backup file: %v", err)
 	}
 
+	b.prune(sourcePath)
 	return backupPath, nil
 }
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so path is either absent or complete
+func writeFileAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), ".backup-*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+	defer os.Remove(tempPath)
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// Latest returns the path of the newest version of sourcePath whose content
+// still matches the hash in its name. Only backups made from this exact path
+// are considered.
+func (b *Backups) Latest(sourcePath string) (string, error) {
+	version, err := b.LatestVersion(sourcePath)
+	return version.Path, err
+}
+
+// LatestVersion works like Latest and also returns when the version was made
+func (b *Backups) LatestVersion(sourcePath string) (BackupVersion, error) {
+	versions, err := b.Versions(sourcePath)
+	if err != nil {
+		return BackupVersion{}, err
+	}
+	for _, version := range versions {
+		if b.valid(version) {
+			return version, nil
+		}
+	}
+
+	if len(versions) > 0 {
+		return BackupVersion{}, fmt.Errorf("no valid backup file found: all %d versions are damaged", len(versions))
+	}
+	return BackupVersion{}, fmt.Errorf("no backup file found for '%s'", filepath.Base(sourcePath))
+}
+
+// Versions lists the backups of sourcePath, newest first
+func (b *Backups) Versions(sourcePath string) ([]BackupVersion, error) {
+	// A folder is only read once the index confirms it holds this source
+	sources, err := b.Sources()
+	if err != nil {
+		return nil, err
+	}
+	if sources[SourceKey(sourcePath)] != sourcePath {
+		return nil, nil
+	}
+
+	dir := b.sourceDir(sourcePath)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	stem, ext := splitName(sourcePath)
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(stem) +
+		fmt.Sprintf(`_(\d{8}-\d{6}\.\d{3})_([0-9a-f]{%d})`, backupHashLength) + regexp.QuoteMeta(ext) + `$`)
+
+	var versions []BackupVersion
+	for _, entry := range entries {
+		match := pattern.FindStringSubmatch(entry.Name())
+		if match == nil || !entry.Type().IsRegular() {
+			continue
+		}
+		createdAt, err := time.Parse(backupTimeLayout, match[1])
+		if err != nil {
+			continue
+		}
+		versions = append(versions, BackupVersion{
+			Path:      filepath.Join(dir, entry.Name()),
+			CreatedAt: createdAt,
+			Hash:      match[2],
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.After(versions[j].CreatedAt)
+	})
+	return versions, nil
+}
+
+// TakeInLegacy moves the backup that earlier releases overwrote at
+// Dir/<file name> into the folder of sourcePath, as the version made at its
+// modification time, so it is listed, fallen back to and pruned like the
+// others. Those backups did not record their source path, so one is only taken
+// in once index.json records it under "legacy/<file name>" and validate
+// accepts its content; until then it is left where it is.
+func (b *Backups) TakeInLegacy(sourcePath string, validate BackupValidator) error {
+	unlock := b.lockSource(sourcePath)
+	defer unlock()
+	return b.takeInLegacy(sourcePath, validate)
+}
+
+// takeInLegacy works like TakeInLegacy for a caller holding the source's lock
+func (b *Backups) takeInLegacy(sourcePath string, validate BackupValidator) error {
+	name := filepath.Base(sourcePath)
+	legacyPath := filepath.Join(b.Dir, name)
+	info, err := os.Lstat(legacyPath)
+	if err != nil || !info.Mode().IsRegular() || validate == nil {
+		return nil
+	}
+	sources, err := b.Sources()
+	if err != nil {
+		return err
+	}
+	if sources[legacyKey(name)] != sourcePath {
+		return nil
+	}
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read legacy backup: %v", err)
+	}
+	if err := validate(data); err != nil {
+		return fmt.Errorf("legacy backup '%s' failed validation and is left where it is: %v", name, err)
+	}
+	if err := b.register(sourcePath); err != nil {
+		return err
+	}
+	if err := os.Rename(legacyPath, b.versionPath(sourcePath, info.ModTime(), contentHash(data))); err != nil {
+		return fmt.Errorf("failed to take in legacy backup: %v", err)
+	}
+	return nil
+}
+
+// legacyKey is the index entry recording the source path of the legacy backup
+// with the given file name
+func legacyKey(name string) string {
+	return "legacy/" + name
+}
+
+// versionPath names the version of sourcePath made at createdAt with content
+// of the given hash
+func (b *Backups) versionPath(sourcePath string, createdAt time.Time, hash string) string {
+	stem, ext := splitName(sourcePath)
+	return filepath.Join(b.sourceDir(sourcePath), fmt.Sprintf("%s_%s_%s%s",
+		stem, createdAt.UTC().Format(backupTimeLayout), hash[:backupHashLength], ext))
+}
+
+// Sources returns the index of backup folders, mapping each SourceKey to the
+// source path its backups were made from
+func (b *Backups) Sources() (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(b.Dir, backupIndexName))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup index: %v", err)
+	}
+
+	sources := map[string]string{}
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to read backup index: %v", err)
+	}
+	return sources, nil
+}
+
+// SourceKey names the backup folder of a source path: the first 16 hex digits
+// of the SHA-256 of the full path
+func SourceKey(sourcePath string) string {
+	return contentHash([]byte(sourcePath))[:16]
+}
+
+func (b *Backups) sourceDir(sourcePath string) string {
+	return filepath.Join(b.Dir, SourceKey(sourcePath))
+}
+
+// lockSource waits until no other backup of sourcePath is being made and
+// returns the function that lets the next one go ahead
+func (b *Backups) lockSource(sourcePath string) func() {
+	mutex, _ := sourceMutexes.LoadOrStore(b.sourceDir(sourcePath), &sync.Mutex{})
+	mutex.(*sync.Mutex).Lock()
+	return mutex.(*sync.Mutex).Unlock
+}
+
+// register creates the backup folder of sourcePath and adds it to the index.
+// It refuses a folder that the index gives to another path.
+func (b *Backups) register(sourcePath string) error {
+	backupIndexMutex.Lock()
+	defer backupIndexMutex.Unlock()
+
+	if err := os.MkdirAll(b.sourceDir(sourcePath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	sources, err := b.Sources()
+	if err != nil {
+		return err
+	}
+	key := SourceKey(sourcePath)
+	switch sources[key] {
+	case sourcePath:
+		return nil
+	case "":
+		sources[key] = sourcePath
+	default:
+		return fmt.Errorf("backup folder %s already belongs to '%s'", key, sources[key])
+	}
+
+	data, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Replace the index in one step so readers never see half of it
+	if err := writeFileAtomic(filepath.Join(b.Dir, backupIndexName), data); err != nil {
+		return fmt.Errorf("failed to write backup index: %v", err)
+	}
+	return nil
+}
+
+// prune removes the versions of sourcePath that fall outside the limits.
+// Failures are ignored; they are retried by the next backup.
+func (b *Backups) prune(sourcePath string) {
+	versions, err := b.Versions(sourcePath)
+	if err != nil {
+		return
+	}
+	cutoff := b.Now().AddDate(0, 0, -b.KeepDays)
+	for i, version := range versions {
+		if i == 0 {
+			continue
+		}
+		tooMany := b.KeepVersions > 0 && i >= b.KeepVersions
+		tooOld := b.KeepDays > 0 && version.CreatedAt.Before(cutoff)
+		if tooMany || tooOld {
+			_ = os.Remove(version.Path)
+		}
+	}
+}
+
+// valid reports whether the version's content still matches its name
+func (b *Backups) valid(version BackupVersion) bool {
+	hash, err := FileHash(version.Path)
+	return err == nil && strings.HasPrefix(hash, version.Hash)
+}
+
+// splitName returns the file name of path without and with only its extension
+func splitName(path string) (string, string) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext), ext
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileHash returns the hex-encoded SHA-256 of the file's content
+func FileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
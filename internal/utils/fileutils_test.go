@@ -0,0 +1,363 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"purchase-record/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBackups returns backups kept in a temporary directory with a clock
+// that advances a minute on every call
+func newTestBackups(t *testing.T) *Backups {
+	t.Helper()
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	return &Backups{
+		Dir: filepath.Join(t.TempDir(), "backup"),
+		Now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+}
+
+func writeSource(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestBackups_Backup(t *testing.T) {
+	backups := newTestBackups(t)
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+
+	writeSource(t, source, "version 1")
+	first, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+	assert.Regexp(t, `PO_20240501-080100\.000_[0-9a-f]{16}\.xlsx$`, first)
+
+	// Unchanged content is not written again
+	again, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	writeSource(t, source, "version 2")
+	second, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	versions, err := backups.Versions(source)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, second, versions[0].Path)
+	assert.Equal(t, first, versions[1].Path)
+
+	data, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Equal(t, "version 1", string(data))
+
+	_, err = backups.Backup(filepath.Join(t.TempDir(), "missing.xlsx"), nil)
+	assert.ErrorContains(t, err, "failed to read source file")
+}
+
+func TestBackups_Retention(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+
+	t.Run("keeps the newest versions", func(t *testing.T) {
+		backups := newTestBackups(t)
+		backups.KeepVersions = 2
+
+		var paths []string
+		for _, content := range []string{"a", "b", "c", "d"} {
+			writeSource(t, source, content)
+			path, err := backups.Backup(source, nil)
+			require.NoError(t, err)
+			paths = append(paths, path)
+		}
+
+		versions, err := backups.Versions(source)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, paths[3], versions[0].Path)
+		assert.Equal(t, paths[2], versions[1].Path)
+	})
+
+	t.Run("removes old versions but never the newest", func(t *testing.T) {
+		backups := newTestBackups(t)
+		backups.KeepDays = 1
+		now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+		backups.Now = func() time.Time { return now }
+
+		writeSource(t, source, "a")
+		_, err := backups.Backup(source, nil)
+		require.NoError(t, err)
+
+		now = now.AddDate(0, 0, 1).Add(time.Hour)
+		writeSource(t, source, "b")
+		newest, err := backups.Backup(source, nil)
+		require.NoError(t, err)
+
+		versions, err := backups.Versions(source)
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		assert.Equal(t, newest, versions[0].Path)
+
+		// Only one version is left however old it gets
+		now = now.AddDate(0, 0, 10)
+		_, err = backups.Backup(source, nil)
+		require.NoError(t, err)
+		versions, err = backups.Versions(source)
+		require.NoError(t, err)
+		assert.Len(t, versions, 1)
+	})
+}
+
+func TestBackups_Latest(t *testing.T) {
+	backups := newTestBackups(t)
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+
+	_, err := backups.Latest(source)
+	assert.EqualError(t, err, "no backup file found for 'PO.xlsx'")
+
+	writeSource(t, source, "good")
+	good, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+	writeSource(t, source, "newer")
+	newer, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+
+	latest, err := backups.Latest(source)
+	require.NoError(t, err)
+	assert.Equal(t, newer, latest)
+
+	// A version whose content no longer matches its name is skipped
+	require.NoError(t, os.WriteFile(newer, []byte("trunc"), 0644))
+	latest, err = backups.Latest(source)
+	require.NoError(t, err)
+	assert.Equal(t, good, latest)
+
+	// Backing up the same content again replaces the damaged version
+	rewritten, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, newer, rewritten)
+	latest, err = backups.Latest(source)
+	require.NoError(t, err)
+	assert.Equal(t, rewritten, latest)
+
+	// Files of other sources are not versions of this one
+	other := filepath.Join(t.TempDir(), "PO_2024.xlsx")
+	writeSource(t, other, "other")
+	_, err = backups.Backup(other, nil)
+	require.NoError(t, err)
+	versions, err := backups.Versions(source)
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+}
+
+func TestBackups_LegacyBackup(t *testing.T) {
+	backups := newTestBackups(t)
+	backups.KeepVersions = 2
+	teamA := filepath.Join(t.TempDir(), "teamA", "orders.xlsx")
+	teamB := filepath.Join(t.TempDir(), "teamB", "orders.xlsx")
+	accept := func([]byte) error { return nil }
+
+	// An earlier release kept one flat copy per file name
+	require.NoError(t, os.MkdirAll(backups.Dir, 0755))
+	legacy := filepath.Join(backups.Dir, "orders.xlsx")
+	writeSource(t, legacy, "last good")
+	madeAt := time.Date(2024, 4, 30, 17, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(legacy, madeAt, madeAt))
+
+	// Sharing its name is not enough for either source to take it
+	for _, source := range []string{teamA, teamB} {
+		require.NoError(t, backups.TakeInLegacy(source, accept))
+		_, err := backups.LatestVersion(source)
+		assert.EqualError(t, err, "no backup file found for 'orders.xlsx'")
+	}
+	assert.FileExists(t, legacy)
+
+	// Once the index names its source, only that source takes it, and only
+	// when its content passes validation
+	index, err := json.Marshal(map[string]string{"legacy/orders.xlsx": teamA})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(backups.Dir, backupIndexName), index, 0644))
+
+	require.NoError(t, backups.TakeInLegacy(teamB, accept))
+	require.NoError(t, backups.TakeInLegacy(teamA, nil))
+	err = backups.TakeInLegacy(teamA, func([]byte) error { return errors.New("no header row") })
+	assert.EqualError(t, err, "legacy backup 'orders.xlsx' failed validation and is left where it is: no header row")
+	assert.FileExists(t, legacy)
+
+	require.NoError(t, backups.TakeInLegacy(teamA, accept))
+	assert.NoFileExists(t, legacy)
+	latest, err := backups.LatestVersion(teamA)
+	require.NoError(t, err)
+	assert.Equal(t, madeAt, latest.CreatedAt)
+	assert.Regexp(t, `orders_20240430-170000\.000_[0-9a-f]{16}\.xlsx$`, latest.Path)
+	data, err := os.ReadFile(latest.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "last good", string(data))
+	_, err = backups.LatestVersion(teamB)
+	assert.Error(t, err)
+
+	// It is pruned like any other version
+	require.NoError(t, os.MkdirAll(filepath.Dir(teamA), 0755))
+	for _, content := range []string{"version 1", "version 2"} {
+		writeSource(t, teamA, content)
+		_, err = backups.Backup(teamA, accept)
+		require.NoError(t, err)
+	}
+	versions, err := backups.Versions(teamA)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.NoFileExists(t, latest.Path)
+}
+
+func TestBackups_SameNameInDifferentFolders(t *testing.T) {
+	backups := newTestBackups(t)
+	teamA := filepath.Join(t.TempDir(), "teamA", "PO.xlsx")
+	teamB := filepath.Join(t.TempDir(), "teamB", "PO.xlsx")
+	require.NoError(t, os.MkdirAll(filepath.Dir(teamA), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(teamB), 0755))
+
+	writeSource(t, teamA, "team A orders")
+	backupA, err := backups.Backup(teamA, nil)
+	require.NoError(t, err)
+
+	// Team B has no backup of its own, so it must not get team A's
+	_, err = backups.Latest(teamB)
+	assert.EqualError(t, err, "no backup file found for 'PO.xlsx'")
+
+	writeSource(t, teamB, "team B orders")
+	backupB, err := backups.Backup(teamB, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, filepath.Dir(backupA), filepath.Dir(backupB))
+
+	latest, err := backups.Latest(teamA)
+	require.NoError(t, err)
+	assert.Equal(t, backupA, latest)
+	latest, err = backups.Latest(teamB)
+	require.NoError(t, err)
+	assert.Equal(t, backupB, latest)
+
+	sources, err := backups.Sources()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{SourceKey(teamA): teamA, SourceKey(teamB): teamB}, sources)
+	assert.Equal(t, filepath.Join(backups.Dir, SourceKey(teamA)), filepath.Dir(backupA))
+}
+
+func TestBackups_IndexGuardsFolders(t *testing.T) {
+	backups := newTestBackups(t)
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+	writeSource(t, source, "orders")
+	_, err := backups.Backup(source, nil)
+	require.NoError(t, err)
+
+	// A folder the index gives to another path is neither read nor written
+	index := filepath.Join(backups.Dir, backupIndexName)
+	require.NoError(t, os.WriteFile(index, []byte(`{"`+SourceKey(source)+`": "other.xlsx"}`), 0644))
+
+	_, err = backups.Latest(source)
+	assert.EqualError(t, err, "no backup file found for 'PO.xlsx'")
+	_, err = backups.Backup(source, nil)
+	assert.ErrorContains(t, err, "already belongs to 'other.xlsx'")
+}
+
+func TestBackups_Validation(t *testing.T) {
+	backups := newTestBackups(t)
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+	validate := func(data []byte) error {
+		if string(data) == "half saved" {
+			return errors.New("invalid header in sheet 'PO'")
+		}
+		return nil
+	}
+
+	writeSource(t, source, "complete")
+	good, err := backups.Backup(source, validate)
+	require.NoError(t, err)
+
+	// Rejected content leaves the previous backup in place
+	writeSource(t, source, "half saved")
+	_, err = backups.Backup(source, validate)
+	assert.EqualError(t, err, "source file failed validation, the previous backup is kept: invalid header in sheet 'PO'")
+
+	latest, err := backups.Latest(source)
+	require.NoError(t, err)
+	assert.Equal(t, good, latest)
+
+	// Nothing but versions and the index is left in the backup folder
+	entries, err := os.ReadDir(filepath.Dir(good))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = os.ReadDir(backups.Dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestReadSource(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+	writeSource(t, source, "workbook")
+
+	workbook, err := ReadSource(source, 0)
+	require.NoError(t, err)
+	assert.Equal(t, source, workbook.Path)
+	assert.Equal(t, "workbook", string(workbook.Data))
+	assert.Equal(t, int64(8), workbook.Size)
+	assert.Equal(t, contentHash([]byte("workbook")), workbook.Hash)
+	assert.False(t, workbook.ModTime.IsZero())
+
+	_, err = ReadSource(source, 8)
+	assert.NoError(t, err)
+	_, err = ReadSource(source, 7)
+	assert.ErrorIs(t, err, ErrSourceTooLarge)
+	assert.EqualError(t, err, "source file is too large: 'PO.xlsx' is over the limit of 7 bytes")
+
+	_, err = ReadSource(filepath.Join(t.TempDir(), "missing.xlsx"), 0)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBackups_BackupData(t *testing.T) {
+	backups := newTestBackups(t)
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+
+	// The bytes given are kept, without the source being read again
+	path, err := backups.BackupData(source, []byte("already read"), nil)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "already read", string(data))
+}
+
+func TestBackups_ConcurrentBackups(t *testing.T) {
+	backups := NewBackups(config.BackupConfig{Dir: filepath.Join(t.TempDir(), "backup")})
+	source := filepath.Join(t.TempDir(), "PO.xlsx")
+
+	// Backups of one source are made one at a time, so the same content is
+	// written once however many requests back it up together
+	var wg sync.WaitGroup
+	paths := make([]string, 10)
+	errs := make([]error, 10)
+	for i := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths[i], errs[i] = backups.BackupData(source, []byte("orders"), nil)
+		}()
+	}
+	wg.Wait()
+
+	for i := range paths {
+		require.NoError(t, errs[i])
+		assert.Equal(t, paths[0], paths[i])
+	}
+	versions, err := backups.Versions(source)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}